@@ -23,6 +23,7 @@ const (
 	ActionSet                ActionType = "set"
 	ActionCloseAndOpenBlinds ActionType = "closeandopenblinds"
 	ActionTilt               ActionType = "tilt"
+	ActionStop               ActionType = "stop"
 )
 
 type Action struct {
@@ -30,18 +31,27 @@ type Action struct {
 	Position int        `json:"position"`
 }
 
+// request is the payload of MQTT commands. The request ID is reported back
+// with the result of the command.
+type request struct {
+	Action
+	RequestID string `json:"requestId,omitempty"`
+}
+
 func Parse(data []byte) (LLCommand, error) {
-	var command Action
+	var command request
 	err := json.Unmarshal(data, &command)
-
-	if err == nil {
-		return command.validate()
+	if err != nil {
+		return LLCommand{}, err
 	}
 
-	return LLCommand{}, err
+	llc, err := command.Validate()
+	llc.RequestID = command.RequestID
+	return llc, err
 }
 
-func (c *Action) validate() (LLCommand, error) {
+// Validate converts the action to a low level command
+func (c *Action) Validate() (LLCommand, error) {
 	llc := LLCommand{}
 	switch strings.ToLower(string(c.Action)) {
 	case string(ActionClose):
@@ -61,6 +71,8 @@ func (c *Action) validate() (LLCommand, error) {
 	case string(ActionTilt):
 		llc.Action = LLActionTilt
 		llc.Position = c.Position
+	case string(ActionStop):
+		llc.Action = LLActionStop
 	default:
 		return llc, fmt.Errorf("invalid action")
 	}
@@ -1,13 +1,43 @@
 package commands
 
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
 type LLAction string
 
 const (
 	LLActionSet  LLAction = "set"
 	LLActionTilt LLAction = "tilt"
+	LLActionStop LLAction = "stop"
+)
+
+// Source identifies where a command came from
+type Source string
+
+const (
+	SourceMQTT       Source = "mqtt"
+	SourceREST       Source = "rest"
+	SourceScene      Source = "scene"
+	SourceSchedule   Source = "schedule"
+	SourceProtection Source = "protection"
+	SourceExternal   Source = "external"
 )
 
 type LLCommand struct {
-	Action   LLAction
-	Position int
+	// ID identifies the command, it is assigned when the command is tracked
+	ID string `json:"id"`
+	// RequestID is an optional ID chosen by the caller
+	RequestID string   `json:"requestId,omitempty"`
+	Action    LLAction `json:"action"`
+	Position  int      `json:"position"`
+	Source    Source   `json:"source,omitempty"`
+}
+
+// NewID returns a random command ID
+func NewID() string {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
 }
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 
+	"github.com/mqtt-home/eltako-to-mqtt-gw/commands"
 	"github.com/philipparndt/go-logger"
 	"github.com/philipparndt/mqtt-gateway/config"
 )
@@ -12,15 +14,144 @@ import (
 var cfg Config
 
 type Config struct {
-	MQTT     config.MQTTConfig `json:"mqtt"`
-	Eltako   Eltako            `json:"eltako"`
-	Web      WebConfig         `json:"web"`
-	LogLevel string            `json:"loglevel,omitempty"`
+	MQTT          config.MQTTConfig   `json:"mqtt"`
+	Eltako        Eltako              `json:"eltako"`
+	Web           WebConfig           `json:"web"`
+	HomeAssistant HomeAssistantConfig `json:"homeassistant"`
+	Groups        []Group             `json:"groups,omitempty"`
+	Scenes        []Scene             `json:"scenes,omitempty"`
+	Schedules     []Schedule          `json:"schedules,omitempty"`
+	SunRules      []SunRule           `json:"sunRules,omitempty"`
+	Protection    []WeatherSensor     `json:"protection,omitempty"`
+	History       HistoryConfig       `json:"history"`
+	// Location is used to calculate the position of the sun
+	Location *Location `json:"location,omitempty"`
+	// DataDir stores runtime data like scenes created through the REST API, defaults to the directory of the config file
+	DataDir  string `json:"dataDir,omitempty"`
+	LogLevel string `json:"loglevel,omitempty"`
+}
+
+// HistoryConfig bounds the recorded position and command history
+type HistoryConfig struct {
+	// MaxEntries is the number of entries kept per actor, the oldest are dropped first
+	MaxEntries int `json:"maxEntries,omitempty"`
 }
 
 type WebConfig struct {
 	Enabled bool `json:"enabled"`
 	Port    int  `json:"port"`
+	// StateMaxAge is the age in milliseconds after which a cached actor state is refreshed
+	StateMaxAge int `json:"stateMaxAge,omitempty"`
+	// AllowedOrigins are the origins allowed for cross-origin requests, defaults to all origins
+	AllowedOrigins []string `json:"allowedOrigins,omitempty"`
+	// Auth enables authentication, the web interface and REST API are open when not set
+	Auth *AuthConfig `json:"auth,omitempty"`
+}
+
+// Roles of API tokens and users
+const (
+	RoleRead    = "read"
+	RoleControl = "control"
+)
+
+type AuthConfig struct {
+	// Tokens are static bearer tokens, e.g. for automations
+	Tokens []APIToken `json:"tokens,omitempty"`
+	// Users log in to the web interface with username and password
+	Users []User `json:"users,omitempty"`
+	// SessionMaxAge is the lifetime of a login session in milliseconds
+	SessionMaxAge int `json:"sessionMaxAge,omitempty"`
+}
+
+type APIToken struct {
+	Name  string `json:"name"`
+	Token string `json:"token"`
+	// Role is "read" or "control" (default)
+	Role string `json:"role,omitempty"`
+}
+
+type User struct {
+	Username string `json:"username"`
+	// Password is either plain text or "sha256:" followed by the hex encoded hash
+	Password string `json:"password"`
+	// Role is "read" or "control" (default)
+	Role string `json:"role,omitempty"`
+}
+
+// Group is a named set of actors that are controlled together
+type Group struct {
+	Name   string   `json:"name"`
+	Actors []string `json:"actors"`
+}
+
+// Scene is a named preset with a command per actor
+type Scene struct {
+	Name    string        `json:"name"`
+	Actions []SceneAction `json:"actions"`
+}
+
+type SceneAction struct {
+	Actor string `json:"actor"`
+	commands.Action
+}
+
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Schedule applies a command to an actor or a group, or activates a scene,
+// at the times given by a cron expression or at sunrise or sunset
+type Schedule struct {
+	Name string `json:"name"`
+	Cron string `json:"cron,omitempty"`
+	// Sun is "sunrise" or "sunset" and can be used instead of Cron
+	Sun string `json:"sun,omitempty"`
+	// Offset in minutes relative to sunrise or sunset
+	Offset int `json:"offset,omitempty"`
+	// Exactly one of Actor, Group and Scene must be set
+	Actor string `json:"actor,omitempty"`
+	Group string `json:"group,omitempty"`
+	Scene string `json:"scene,omitempty"`
+	commands.Action
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+// SunRule applies a command to its actors while the sun shines on their
+// facade and optionally another one when it does not anymore
+type SunRule struct {
+	Name   string   `json:"name"`
+	Actors []string `json:"actors"`
+	// AzimuthTolerance is the maximum difference in degrees between the sun
+	// azimuth and the facade azimuth of an actor, defaults to 60
+	AzimuthTolerance float64 `json:"azimuthTolerance,omitempty"`
+	// MinElevation is the minimum elevation of the sun in degrees
+	MinElevation float64 `json:"minElevation,omitempty"`
+	commands.Action
+	Release *commands.Action `json:"release,omitempty"`
+}
+
+// WeatherSensor locks its actors and drives them to a safe position while
+// the value published on Topic exceeds Threshold
+type WeatherSensor struct {
+	Name  string `json:"name"`
+	Topic string `json:"topic"`
+	// Property is the path of the value in a JSON payload (e.g. "wind.speed"),
+	// the whole payload is used when empty
+	Property  string  `json:"property,omitempty"`
+	Threshold float64 `json:"threshold"`
+	// HoldOff is the time in milliseconds the value must stay below the
+	// threshold before the actors are released, defaults to 600000
+	HoldOff int `json:"holdOff,omitempty"`
+	// Actors affected by the sensor, all actors when empty
+	Actors []string `json:"actors,omitempty"`
+	// Action drives the actors to their safe position, defaults to open
+	commands.Action
+}
+
+type HomeAssistantConfig struct {
+	Enabled         bool   `json:"enabled"`
+	DiscoveryPrefix string `json:"discoveryPrefix,omitempty"`
 }
 
 type BlindsConfig struct {
@@ -35,6 +166,28 @@ type Device struct {
 	Password     string       `json:"password"`
 	Name         string       `json:"name"`
 	BlindsConfig BlindsConfig `json:"blindsConfig"`
+	// PollingInterval is the idle polling interval in milliseconds, defaults to eltako.polling-interval
+	PollingInterval int `json:"polling-interval,omitempty"`
+	// FastPollingInterval is used in milliseconds while the actor is moving, defaults to eltako.fast-polling-interval
+	FastPollingInterval int `json:"fast-polling-interval,omitempty"`
+	// FacadeAzimuth is the direction the window faces in degrees clockwise from north
+	FacadeAzimuth *float64   `json:"facadeAzimuth,omitempty"`
+	Interlock     *Interlock `json:"interlock,omitempty"`
+	// CommandPolicy overrides eltako.commandPolicy for this actor
+	CommandPolicy string `json:"commandPolicy,omitempty"`
+}
+
+// Interlock refuses commands while a window or door contact is open
+type Interlock struct {
+	Topic string `json:"topic"`
+	// Property is the path of the value in a JSON payload (e.g. "contact"),
+	// the whole payload is used when empty
+	Property string `json:"property,omitempty"`
+	// OpenPayload is the value the contact reports while open, e.g. "open" or "false"
+	OpenPayload string `json:"openPayload"`
+	// MinPosition is the lowest position allowed while the contact is open.
+	// All commands except stop are refused when not set.
+	MinPosition *int `json:"minPosition,omitempty"`
 }
 
 func (d *Device) String() string {
@@ -42,9 +195,54 @@ func (d *Device) String() string {
 }
 
 type Eltako struct {
-	Devices         []Device `json:"devices"`
-	PollingInterval int      `json:"polling-interval"`
-	OptimizeTilt    *bool    `json:"optimizeTilt,omitempty"`
+	Devices             []Device `json:"devices"`
+	PollingInterval     int      `json:"polling-interval"`
+	FastPollingInterval int      `json:"fast-polling-interval,omitempty"`
+	OptimizeTilt        *bool    `json:"optimizeTilt,omitempty"`
+	// CommandPolicy decides what happens to a command arriving while another
+	// one is in flight: "supersede" (default) cancels the running command,
+	// "queue" runs the commands one after the other.
+	CommandPolicy string `json:"commandPolicy,omitempty"`
+}
+
+const (
+	CommandPolicySupersede = "supersede"
+	CommandPolicyQueue     = "queue"
+)
+
+// validateAuth sets the default values of the authentication settings and
+// rejects incomplete credentials
+func validateAuth(auth *AuthConfig) error {
+	if auth.SessionMaxAge == 0 {
+		auth.SessionMaxAge = 7 * 24 * 60 * 60 * 1000
+	}
+
+	validRole := func(role *string) bool {
+		if *role == "" {
+			*role = RoleControl
+		}
+		return *role == RoleRead || *role == RoleControl
+	}
+
+	for i := range auth.Tokens {
+		token := &auth.Tokens[i]
+		if token.Token == "" {
+			return fmt.Errorf("API token %s: token must not be empty", token.Name)
+		}
+		if !validRole(&token.Role) {
+			return fmt.Errorf("API token %s: unknown role %q", token.Name, token.Role)
+		}
+	}
+	for i := range auth.Users {
+		user := &auth.Users[i]
+		if user.Username == "" || user.Password == "" {
+			return fmt.Errorf("users need a username and a password")
+		}
+		if !validRole(&user.Role) {
+			return fmt.Errorf("user %s: unknown role %q", user.Username, user.Role)
+		}
+	}
+	return nil
 }
 
 func LoadConfig(file string) (Config, error) {
@@ -68,6 +266,82 @@ func LoadConfig(file string) (Config, error) {
 		cfg.LogLevel = "info"
 	}
 
+	if cfg.DataDir == "" {
+		cfg.DataDir = filepath.Dir(file)
+	}
+
+	if cfg.Web.StateMaxAge == 0 {
+		cfg.Web.StateMaxAge = 300000
+	}
+
+	if len(cfg.Web.AllowedOrigins) == 0 {
+		cfg.Web.AllowedOrigins = []string{"*"}
+	}
+
+	if auth := cfg.Web.Auth; auth != nil {
+		if err := validateAuth(auth); err != nil {
+			logger.Error("Invalid configuration", err)
+			return Config{}, err
+		}
+	}
+
+	if cfg.History.MaxEntries == 0 {
+		cfg.History.MaxEntries = 5000
+	}
+
+	if cfg.HomeAssistant.DiscoveryPrefix == "" {
+		cfg.HomeAssistant.DiscoveryPrefix = "homeassistant"
+	}
+
+	if cfg.Eltako.FastPollingInterval == 0 {
+		cfg.Eltako.FastPollingInterval = 2000
+	}
+
+	if cfg.Eltako.CommandPolicy == "" {
+		cfg.Eltako.CommandPolicy = CommandPolicySupersede
+	}
+
+	for i := range cfg.Eltako.Devices {
+		device := &cfg.Eltako.Devices[i]
+		if device.PollingInterval == 0 {
+			device.PollingInterval = cfg.Eltako.PollingInterval
+		}
+		if device.FastPollingInterval == 0 {
+			device.FastPollingInterval = cfg.Eltako.FastPollingInterval
+		}
+		if device.CommandPolicy == "" {
+			device.CommandPolicy = cfg.Eltako.CommandPolicy
+		}
+		if device.CommandPolicy != CommandPolicySupersede && device.CommandPolicy != CommandPolicyQueue {
+			err = fmt.Errorf("device %s: unknown command policy %q", device.Name, device.CommandPolicy)
+			logger.Error("Invalid configuration", err)
+			return Config{}, err
+		}
+	}
+
+	for i := range cfg.Schedules {
+		if cfg.Schedules[i].Enabled == nil {
+			enabled := true
+			cfg.Schedules[i].Enabled = &enabled
+		}
+	}
+
+	for i := range cfg.SunRules {
+		if cfg.SunRules[i].AzimuthTolerance == 0 {
+			cfg.SunRules[i].AzimuthTolerance = 60
+		}
+	}
+
+	for i := range cfg.Protection {
+		sensor := &cfg.Protection[i]
+		if sensor.HoldOff == 0 {
+			sensor.HoldOff = 600000
+		}
+		if sensor.Action.Action == "" {
+			sensor.Action.Action = commands.ActionOpen
+		}
+	}
+
 	// Set default value for OptimizeTilt if not specified in config
 	if cfg.Eltako.OptimizeTilt == nil {
 		defaultOptimizeTilt := true
@@ -87,6 +361,16 @@ func (c *Eltako) GetBySN(sn string) *Device {
 	return nil
 }
 
+func (c *Eltako) GetByName(name string) *Device {
+	for i := range c.Devices {
+		if c.Devices[i].Name == name {
+			return &c.Devices[i]
+		}
+	}
+
+	return nil
+}
+
 func (e Eltako) GetOptimizeTilt() bool {
 	if e.OptimizeTilt == nil {
 		return true // default value
@@ -42,6 +42,19 @@ type EltakoDiscovery struct {
 	actors map[string]Actor
 	mu     sync.Mutex
 	events chan<- ActorEvent
+
+	lastBrowse time.Time
+	browseErr  error
+	statusMu   sync.Mutex
+}
+
+// browseOverdue is the age of the last successful browse after which the
+// discovery is reported as not running
+const browseOverdue = 30 * time.Second
+
+type Status struct {
+	LastBrowse *time.Time `json:"lastBrowse,omitempty"`
+	Actors     int        `json:"actors"`
 }
 
 func New(events chan<- ActorEvent) *EltakoDiscovery {
@@ -151,12 +164,48 @@ func (d *EltakoDiscovery) Start() {
 				log.Printf("Browse failed: %v", err)
 			}
 			<-ctx.Done()
+			d.browsed(err)
 			cancel()
 			time.Sleep(5 * time.Second)
 		}
 	}()
 }
 
+func (d *EltakoDiscovery) browsed(err error) {
+	d.statusMu.Lock()
+	defer d.statusMu.Unlock()
+
+	d.browseErr = err
+	if err == nil {
+		d.lastBrowse = time.Now()
+	}
+}
+
+// Check reports the discovery as down when the last browse failed or the
+// browse loop stopped
+func (d *EltakoDiscovery) Check() (any, error) {
+	d.mu.Lock()
+	status := Status{Actors: len(d.actors)}
+	d.mu.Unlock()
+
+	d.statusMu.Lock()
+	defer d.statusMu.Unlock()
+
+	if !d.lastBrowse.IsZero() {
+		lastBrowse := d.lastBrowse
+		status.LastBrowse = &lastBrowse
+	}
+	switch {
+	case d.browseErr != nil:
+		return status, fmt.Errorf("browse failed: %w", d.browseErr)
+	case d.lastBrowse.IsZero():
+		return status, fmt.Errorf("browse not completed yet")
+	case time.Since(d.lastBrowse) > browseOverdue:
+		return status, fmt.Errorf("no browse since %s", d.lastBrowse.Format(time.RFC3339))
+	}
+	return status, nil
+}
+
 func parseTXT(txt []string) map[string]string {
 	props := make(map[string]string)
 	for _, entry := range txt {
@@ -0,0 +1,644 @@
+package eltako
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/commands"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/config"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/eltako/fake"
+)
+
+func newTestActor(t *testing.T, position int) (*ShadingActor, *fake.Server) {
+	t.Helper()
+
+	server := fake.NewServer(position)
+	t.Cleanup(server.Close)
+
+	actor := NewShadingActor(config.Device{
+		Ip:       server.Address(),
+		Username: fake.Username,
+		Password: fake.Password,
+		Name:     t.Name(),
+		BlindsConfig: config.BlindsConfig{
+			TiltDownPercentage: 4,
+			TiltUpPercentage:   3,
+		},
+	})
+	if err := actor.init(); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	actor.recordSuccess()
+	return actor, server
+}
+
+func tiltTo(position int) commands.LLCommand {
+	return commands.LLCommand{Action: commands.LLActionTilt, Position: position}
+}
+
+func TestSetAndWaitForPosition(t *testing.T) {
+	actor, server := newTestActor(t, 100)
+
+	wg := sync.WaitGroup{}
+	err := actor.SetAndWaitForPosition(context.Background(), &wg, 20, 10)
+	if err != nil {
+		t.Fatalf("SetAndWaitForPosition failed: %v", err)
+	}
+	wg.Wait()
+
+	if server.Position() != 20 {
+		t.Errorf("expected device at 20, got %d", server.Position())
+	}
+	if actor.CachedState().Position != 20 {
+		t.Errorf("expected cached position 20, got %d", actor.CachedState().Position)
+	}
+}
+
+func TestTilt(t *testing.T) {
+	tests := []struct {
+		name    string
+		start   int
+		targets []int
+	}{
+		{"from above", 100, []int{50, 53}},
+		{"from below", 0, []int{50, 46}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actor, server := newTestActor(t, tt.start)
+
+			if err := actor.Apply(tiltTo(50)); err != nil {
+				t.Fatalf("Tilt failed: %v", err)
+			}
+
+			if got := server.TargetsSent(); !reflect.DeepEqual(got, tt.targets) {
+				t.Errorf("expected targets %v, got %v", tt.targets, got)
+			}
+			if !actor.Tilted || actor.TiltPosition != 50 {
+				t.Errorf("expected actor tilted at 50, got tilted=%v at %d", actor.Tilted, actor.TiltPosition)
+			}
+		})
+	}
+}
+
+func TestTiltSkipsWhenAlreadyTilted(t *testing.T) {
+	actor, server := newTestActor(t, 100)
+
+	if err := actor.Apply(tiltTo(50)); err != nil {
+		t.Fatalf("Tilt failed: %v", err)
+	}
+	sent := len(server.TargetsSent())
+
+	if err := actor.Apply(tiltTo(50)); err != nil {
+		t.Fatalf("second Tilt failed: %v", err)
+	}
+	if len(server.TargetsSent()) != sent {
+		t.Errorf("expected no further commands, got %v", server.TargetsSent())
+	}
+}
+
+func TestStopMovementCancelsTilt(t *testing.T) {
+	actor, server := newTestActor(t, 100)
+	server.TravelTime = 5 * time.Second
+
+	result := make(chan error, 1)
+	go func() {
+		result <- actor.Apply(tiltTo(0))
+	}()
+
+	time.Sleep(700 * time.Millisecond)
+	if err := actor.Apply(commands.LLCommand{Action: commands.LLActionStop}); err != nil {
+		t.Fatalf("stop failed: %v", err)
+	}
+
+	select {
+	case err := <-result:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected tilt to be cancelled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("tilt did not return after stop")
+	}
+
+	targets := server.TargetsSent()
+	if len(targets) != 2 || targets[1] == 0 {
+		t.Errorf("expected stop to re-target to the current position, got %v", targets)
+	}
+	if actor.Tilted {
+		t.Error("expected actor not to be tilted")
+	}
+}
+
+func TestReauthenticateOnUnauthorized(t *testing.T) {
+	actor, server := newTestActor(t, 40)
+	server.ExpireTokens()
+
+	if _, err := actor.getPosition(); err == nil {
+		t.Fatal("expected request with expired token to fail")
+	}
+	if actor.Health() != HealthDegraded {
+		t.Errorf("expected health %s after re-login, got %s", HealthDegraded, actor.Health())
+	}
+
+	position, err := actor.getPosition()
+	if err != nil {
+		t.Fatalf("expected request after re-login to succeed: %v", err)
+	}
+	if position != 40 || actor.Health() != HealthOnline {
+		t.Errorf("expected position 40 and health online, got %d and %s", position, actor.Health())
+	}
+}
+
+func TestSetAddressWhileLogging(t *testing.T) {
+	server := fake.NewServer(40)
+	t.Cleanup(server.Close)
+	actor := NewShadingActor(config.Device{Ip: "192.0.2.1", Name: t.Name()})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		actor.SetAddress(server.Address())
+	}()
+	_ = actor.String()
+	_ = actor.DisplayName()
+	<-done
+
+	if actor.Address() != server.Address() {
+		t.Errorf("expected address %s, got %s", server.Address(), actor.Address())
+	}
+}
+
+func TestOfflineAfterConsecutiveFailures(t *testing.T) {
+	actor, server := newTestActor(t, 40)
+	server.Fail(fake.OpGetPosition, http.StatusInternalServerError, maxConsecutiveFailures)
+
+	for i := 0; i < maxConsecutiveFailures; i++ {
+		if _, err := actor.getPosition(); err == nil {
+			t.Fatal("expected injected failure")
+		}
+	}
+	if actor.Health() != HealthOffline {
+		t.Errorf("expected health %s, got %s", HealthOffline, actor.Health())
+	}
+
+	if _, err := actor.getPosition(); err != nil {
+		t.Fatalf("expected recovery: %v", err)
+	}
+	if actor.Health() != HealthOnline {
+		t.Errorf("expected health %s, got %s", HealthOnline, actor.Health())
+	}
+}
+
+func TestPollingReportsExternalMovement(t *testing.T) {
+	server := fake.NewServer(100)
+	t.Cleanup(server.Close)
+
+	actor := NewShadingActor(config.Device{
+		Ip:       server.Address(),
+		Username: fake.Username,
+		Password: fake.Password,
+		Name:     t.Name(),
+
+		PollingInterval:     50,
+		FastPollingInterval: 50,
+	})
+
+	events := Events.Subscribe(t.Name(), 100)
+	defer events.Close()
+
+	actor.Start()
+	server.MoveTo(30)
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-events.C:
+			if event.Actor == actor && event.Type == EventPositionChanged && event.Position == 30 {
+				state := actor.CachedState()
+				if state.LastCommandSource != commands.SourceExternal {
+					t.Errorf("expected source %s, got %s", commands.SourceExternal, state.LastCommandSource)
+				}
+				return
+			}
+		case <-timeout:
+			t.Fatalf("position change not reported, device at %d", server.Position())
+		}
+	}
+}
+
+func TestPollingUsesFastIntervalWhileMoving(t *testing.T) {
+	server := fake.NewServer(100)
+	t.Cleanup(server.Close)
+
+	actor := NewShadingActor(config.Device{
+		Ip:       server.Address(),
+		Username: fake.Username,
+		Password: fake.Password,
+		Name:     t.Name(),
+
+		PollingInterval:     int(time.Hour.Milliseconds()),
+		FastPollingInterval: 50,
+	})
+
+	events := Events.Subscribe(t.Name(), 100)
+	defer events.Close()
+
+	actor.Start()
+	for !actor.IsAvailable() {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := actor.SetPosition(30); err != nil {
+		t.Fatalf("SetPosition failed: %v", err)
+	}
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-events.C:
+			if event.Actor == actor && event.Type == EventMovementStopped {
+				if event.Position != 30 {
+					t.Errorf("expected movement to stop at 30, got %d", event.Position)
+				}
+				if state := actor.CachedState(); state.Moving || state.Direction != DirectionStopped {
+					t.Errorf("expected stopped state, got moving=%v direction=%s", state.Moving, state.Direction)
+				}
+				return
+			}
+		case <-timeout:
+			t.Fatalf("movement end not detected, device at %d", server.Position())
+		}
+	}
+}
+
+func TestTiltKeptWhilePolling(t *testing.T) {
+	server := fake.NewServer(100)
+	t.Cleanup(server.Close)
+	server.TravelTime = 2 * time.Second
+
+	actor := NewShadingActor(config.Device{
+		Ip:       server.Address(),
+		Username: fake.Username,
+		Password: fake.Password,
+		Name:     t.Name(),
+		BlindsConfig: config.BlindsConfig{
+			TiltDownPercentage: 4,
+			TiltUpPercentage:   3,
+		},
+
+		PollingInterval:     int(time.Hour.Milliseconds()),
+		FastPollingInterval: 50,
+	})
+
+	actor.Start()
+	for !actor.IsAvailable() {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := actor.Apply(tiltTo(80)); err != nil {
+		t.Fatalf("tilt failed: %v", err)
+	}
+	time.Sleep(500 * time.Millisecond)
+
+	state := actor.CachedState()
+	if !state.Tilted || state.TiltPosition != 80 {
+		t.Errorf("expected actor tilted at 80, got tilted=%v at %d", state.Tilted, state.TiltPosition)
+	}
+	if state.Position != 83 || server.Position() != 83 {
+		t.Errorf("expected position 83, got %d with device at %d", state.Position, server.Position())
+	}
+}
+
+func TestMovementStartReportsReadPosition(t *testing.T) {
+	actor, server := newTestActor(t, 100)
+	server.TravelTime = 10 * time.Second
+	if _, err := actor.getPosition(); err != nil {
+		t.Fatalf("getPosition failed: %v", err)
+	}
+
+	events := Events.Subscribe(t.Name(), 100)
+	defer events.Close()
+
+	if _, err := actor.SetPosition(0); err != nil {
+		t.Fatalf("SetPosition failed: %v", err)
+	}
+
+	message := NewPositionMessage(actor.CachedState())
+	if message.Position != 100 || !message.Moving || message.TargetPosition == nil || *message.TargetPosition != 0 {
+		t.Errorf("expected position 100 moving to 0, got %+v", message)
+	}
+	for event := range events.C {
+		if event.Actor == actor && event.Type == EventMovementStarted {
+			if event.Position != 100 {
+				t.Errorf("expected movement to start at 100, got %d", event.Position)
+			}
+			break
+		}
+	}
+}
+
+func TestApplyRejectedWhileLocked(t *testing.T) {
+	actor, server := newTestActor(t, 100)
+	actor.Lock("test", "wind protection")
+
+	var locked *LockedError
+	err := actor.Apply(commands.LLCommand{Action: commands.LLActionSet, Position: 0, Source: commands.SourceREST})
+	if !errors.As(err, &locked) || locked.Reasons[0] != "wind protection" {
+		t.Fatalf("expected LockedError, got %v", err)
+	}
+	if len(server.TargetsSent()) != 0 {
+		t.Errorf("expected no command to be sent, got %v", server.TargetsSent())
+	}
+
+	id, err := actor.Submit(commands.LLCommand{ID: t.Name() + "-submit", Action: commands.LLActionSet, Position: 0, Source: commands.SourceREST})
+	if !errors.As(err, &locked) {
+		t.Errorf("expected Submit to report the refusal, got %v", err)
+	}
+	if record, ok := Commands.Get(id); !ok || record.Status != CommandFailed {
+		t.Errorf("expected the refused command to be tracked as failed, got %+v", record)
+	}
+
+	err = actor.Apply(commands.LLCommand{Action: commands.LLActionSet, Position: 100, Source: commands.SourceProtection})
+	if err != nil {
+		t.Errorf("expected protection command to pass, got %v", err)
+	}
+
+	actor.Unlock("test")
+	if actor.LockState().Locked {
+		t.Error("expected actor to be unlocked")
+	}
+}
+
+func TestInterlockRefusesClosing(t *testing.T) {
+	actor, server := newTestActor(t, 100)
+	minPosition := 80
+	actor.device.Interlock = &config.Interlock{Topic: "contact", OpenPayload: "open", MinPosition: &minPosition}
+	actor.SetContactOpen(true)
+
+	var interlock *InterlockError
+	err := actor.Apply(commands.LLCommand{Action: commands.LLActionSet, Position: 20, Source: commands.SourceMQTT})
+	if !errors.As(err, &interlock) {
+		t.Fatalf("expected InterlockError, got %v", err)
+	}
+	if len(server.TargetsSent()) != 0 {
+		t.Errorf("expected no command to be sent, got %v", server.TargetsSent())
+	}
+
+	if err := actor.Apply(commands.LLCommand{Action: commands.LLActionSet, Position: 90, Source: commands.SourceMQTT}); err != nil {
+		t.Errorf("expected position above the minimum to pass, got %v", err)
+	}
+
+	// The tilt would end at 76 after moving down by the tilt offset
+	err = actor.Apply(commands.LLCommand{Action: commands.LLActionTilt, Position: 80, Source: commands.SourceMQTT})
+	if !errors.As(err, &interlock) {
+		t.Errorf("expected tilt ending below the minimum to be refused, got %v", err)
+	}
+
+	actor.SetContactOpen(false)
+	if err := actor.Apply(commands.LLCommand{Action: commands.LLActionSet, Position: 20, Source: commands.SourceMQTT}); err != nil {
+		t.Errorf("expected command to pass with closed contact, got %v", err)
+	}
+}
+
+func TestExecute(t *testing.T) {
+	t.Run("reached", func(t *testing.T) {
+		actor, _ := newTestActor(t, 100)
+		actor.device.FastPollingInterval = 50
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		result := actor.Execute(ctx, commands.LLCommand{Action: commands.LLActionSet, Position: 20})
+		if result.Outcome != OutcomeReached || result.Position != 20 {
+			t.Errorf("unexpected result %+v", result)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		actor, server := newTestActor(t, 100)
+		actor.device.FastPollingInterval = 50
+		server.TravelTime = 10 * time.Second
+
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+		result := actor.Execute(ctx, commands.LLCommand{Action: commands.LLActionSet, Position: 0})
+		if result.Outcome != OutcomeTimeout {
+			t.Errorf("unexpected result %+v", result)
+		}
+	})
+
+	t.Run("superseded", func(t *testing.T) {
+		actor, server := newTestActor(t, 100)
+		server.TravelTime = 5 * time.Second
+
+		result := make(chan Result, 1)
+		go func() {
+			result <- actor.Execute(context.Background(), commands.LLCommand{Action: commands.LLActionTilt, Position: 0})
+		}()
+		time.Sleep(700 * time.Millisecond)
+		actor.Submit(commands.LLCommand{Action: commands.LLActionSet, Position: 80})
+
+		if r := <-result; r.Outcome != OutcomeSuperseded {
+			t.Errorf("unexpected result %+v", r)
+		}
+	})
+
+	t.Run("device error", func(t *testing.T) {
+		actor, server := newTestActor(t, 100)
+		server.Fail(fake.OpSetPosition, http.StatusInternalServerError, 3)
+
+		result := actor.Execute(context.Background(), commands.LLCommand{Action: commands.LLActionSet, Position: 0})
+		if result.Outcome != OutcomeDeviceError || result.StatusCode != http.StatusInternalServerError {
+			t.Errorf("unexpected result %+v", result)
+		}
+	})
+}
+
+func TestCommandLifecycle(t *testing.T) {
+	actor, server := newTestActor(t, 100)
+	server.TravelTime = 5 * time.Second
+
+	tilt := commands.LLCommand{ID: t.Name() + "-tilt", RequestID: "req-1", Action: commands.LLActionTilt, Position: 0}
+	id, _ := actor.Submit(tilt)
+	if id != tilt.ID {
+		t.Fatalf("expected ID %s, got %s", tilt.ID, id)
+	}
+	if record, ok := Commands.Get(id); !ok || record.RequestID != "req-1" {
+		t.Fatalf("expected the command to be tracked right away, got %+v", record)
+	}
+
+	time.Sleep(700 * time.Millisecond)
+	if record, _ := Commands.Get(id); record.Status != CommandRunning {
+		t.Errorf("expected tilt to be running, got %s", record.Status)
+	}
+
+	stop := commands.LLCommand{ID: t.Name() + "-stop", Action: commands.LLActionStop}
+	if err := actor.Apply(stop); err != nil {
+		t.Fatalf("stop failed: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		record, _ := Commands.Get(id)
+		if record.Status.IsFinished() {
+			if record.Status != CommandCancelled {
+				t.Errorf("expected tilt to be cancelled, got %s", record.Status)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("tilt did not finish after stop")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if record, _ := Commands.Get(stop.ID); record.Status != CommandSucceeded {
+		t.Errorf("expected stop to succeed, got %s", record.Status)
+	}
+	if records := Commands.ForActor(actor.Name); len(records) != 2 || records[0].ID != stop.ID {
+		t.Errorf("expected newest command first, got %+v", records)
+	}
+}
+
+func TestSetSupersedesTilt(t *testing.T) {
+	actor, server := newTestActor(t, 100)
+	server.TravelTime = 5 * time.Second
+
+	tilt, _ := actor.Submit(commands.LLCommand{ID: t.Name() + "-tilt", Action: commands.LLActionTilt, Position: 0})
+	time.Sleep(700 * time.Millisecond)
+
+	set := commands.LLCommand{ID: t.Name() + "-set", Action: commands.LLActionSet, Position: 80}
+	if err := actor.Apply(set); err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+
+	if record, _ := Commands.Get(tilt); record.Status != CommandSuperseded {
+		t.Errorf("expected tilt to be superseded, got %s", record.Status)
+	}
+	if got := server.TargetsSent(); !reflect.DeepEqual(got, []int{0, 80}) {
+		t.Errorf("expected targets [0 80], got %v", got)
+	}
+	if actor.Tilted {
+		t.Error("expected actor not to be tilted")
+	}
+}
+
+func TestQueuePolicyRunsCommandsInOrder(t *testing.T) {
+	actor, server := newTestActor(t, 100)
+	actor.device.CommandPolicy = config.CommandPolicyQueue
+
+	tilt, _ := actor.Submit(commands.LLCommand{ID: t.Name() + "-tilt", Action: commands.LLActionTilt, Position: 50})
+	set := commands.LLCommand{ID: t.Name() + "-set", Action: commands.LLActionSet, Position: 80}
+	if err := actor.Apply(set); err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+
+	if record, _ := Commands.Get(tilt); record.Status != CommandSucceeded {
+		t.Errorf("expected tilt to succeed, got %s", record.Status)
+	}
+	if got := server.TargetsSent(); !reflect.DeepEqual(got, []int{50, 53, 80}) {
+		t.Errorf("expected targets [50 53 80], got %v", got)
+	}
+}
+
+func TestQueuedCommandRefusedAfterLock(t *testing.T) {
+	actor, server := newTestActor(t, 100)
+	actor.device.CommandPolicy = config.CommandPolicyQueue
+	server.TravelTime = 2 * time.Second
+
+	_, tilt, _ := actor.submit(commands.LLCommand{Action: commands.LLActionTilt, Position: 50, Source: commands.SourceREST})
+	_, set, err := actor.submit(commands.LLCommand{Action: commands.LLActionSet, Position: 0, Source: commands.SourceREST})
+	if err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	actor.Lock("test", "wind protection")
+
+	if err := <-tilt; err != nil {
+		t.Errorf("expected running tilt to finish, got %v", err)
+	}
+	var locked *LockedError
+	if err := <-set; !errors.As(err, &locked) {
+		t.Errorf("expected queued set to be refused, got %v", err)
+	}
+	if got := server.TargetsSent(); !reflect.DeepEqual(got, []int{50, 53}) {
+		t.Errorf("expected targets [50 53], got %v", got)
+	}
+}
+
+func TestStateSurvivesRestart(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "actors.json")
+	Persisted = NewStateStore(file)
+	t.Cleanup(func() { Persisted = nil })
+
+	actor, server := newTestActor(t, 100)
+	tilt := commands.LLCommand{ID: t.Name() + "-tilt", Action: commands.LLActionTilt, Position: 50, Source: commands.SourceREST}
+	if err := actor.Apply(tilt); err != nil {
+		t.Fatalf("tilt failed: %v", err)
+	}
+
+	Persisted.Flush()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", file, err)
+	}
+	if !strings.Contains(string(data), `"id": "`+tilt.ID+`"`) || !strings.Contains(string(data), `"action": "tilt"`) {
+		t.Errorf("expected camel case keys for the last command, got %s", data)
+	}
+
+	store, err := OpenStateStore(file)
+	if err != nil {
+		t.Fatalf("OpenStateStore failed: %v", err)
+	}
+	Persisted = store
+
+	restarted := NewShadingActor(actor.device)
+	if !restarted.Tilted || restarted.TiltPosition != 50 || restarted.Position != 53 {
+		t.Errorf("expected restored tilt at 50 and position 53, got tilted=%v at %d, position %d",
+			restarted.Tilted, restarted.TiltPosition, restarted.Position)
+	}
+	if restarted.lastCommand == nil || restarted.lastCommand.ID != tilt.ID {
+		t.Errorf("expected last command %s, got %+v", tilt.ID, restarted.lastCommand)
+	}
+	if restarted.Health() != "" {
+		t.Errorf("expected health not to be restored, got %s", restarted.Health())
+	}
+
+	if err := restarted.init(); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	sent := len(server.TargetsSent())
+	if err := restarted.Apply(tiltTo(50)); err != nil {
+		t.Fatalf("Tilt failed: %v", err)
+	}
+	if len(server.TargetsSent()) != sent {
+		t.Errorf("expected restored tilt state to skip the tilt, got %v", server.TargetsSent())
+	}
+}
+
+func TestStateStoreIgnoresOlderSaves(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "actors.json")
+	store := NewStateStore(file)
+
+	now := time.Now()
+	store.save("living", StoredState{Position: 20, UpdatedAt: now}, 2)
+	store.save("living", StoredState{Position: 80, UpdatedAt: now}, 1)
+	store.Flush()
+
+	restored, err := OpenStateStore(file)
+	if err != nil {
+		t.Fatalf("OpenStateStore failed: %v", err)
+	}
+	if state, ok := restored.Get("living"); !ok || state.Position != 20 {
+		t.Errorf("expected the newer position 20, got %+v", state)
+	}
+}
@@ -4,12 +4,14 @@ import (
 	"crypto/tls"
 	"io"
 	"net/http"
+	"sync"
 )
 
 type HTTPClient struct {
 	BaseURL   string
 	Client    *http.Client
 	AuthToken string
+	mu        sync.RWMutex
 }
 
 func NewHTTPClient(baseURL string) *HTTPClient {
@@ -29,13 +31,29 @@ func NewHTTPClient(baseURL string) *HTTPClient {
 }
 
 func (c *HTTPClient) SetAuthToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	c.AuthToken = token
 }
 
 func (c *HTTPClient) GetAuthToken() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	return c.AuthToken
 }
 
+// SetBaseURL points the client to a new address. The token is dropped as it
+// is only valid for the device it was issued by.
+func (c *HTTPClient) SetBaseURL(baseURL string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.BaseURL = baseURL
+	c.AuthToken = ""
+}
+
 func (c *HTTPClient) Get(url string) (*http.Response, error) {
 	return c.NewRequest("GET", url, nil)
 }
@@ -49,15 +67,26 @@ func (c *HTTPClient) Put(url string, body io.Reader) (*http.Response, error) {
 }
 
 func (c *HTTPClient) NewRequest(method, url string, body io.Reader) (*http.Response, error) {
-	req, err := http.NewRequest(method, c.BaseURL+url, body)
+	c.mu.RLock()
+	baseURL := c.BaseURL
+	token := c.AuthToken
+	c.mu.RUnlock()
+
+	req, err := http.NewRequest(method, baseURL+url, body)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	if c.AuthToken != "" {
-		req.Header.Set("Authorization", c.AuthToken)
+	if token != "" {
+		req.Header.Set("Authorization", token)
 	}
 
-	return c.Client.Do(req)
+	resp, err := c.Client.Do(req)
+	code := 0
+	if resp != nil {
+		code = resp.StatusCode
+	}
+	httpRequests.WithLabelValues(method, statusLabel(code, err)).Inc()
+	return resp, err
 }
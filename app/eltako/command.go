@@ -1,6 +1,7 @@
 package eltako
 
 import (
+	"context"
 	"sync"
 
 	"github.com/mqtt-home/eltako-to-mqtt-gw/commands"
@@ -8,26 +9,122 @@ import (
 	"github.com/philipparndt/go-logger"
 )
 
-func (s *ShadingActor) Apply(command commands.LLCommand) {
+// Submit tracks the command as queued and hands it to the worker of the
+// actor without waiting for it. It returns the ID of the command and the
+// error of a command that was refused right away.
+func (s *ShadingActor) Submit(command commands.LLCommand) (string, error) {
+	command, _, err := s.submit(command)
+	return command.ID, err
+}
+
+// Apply hands the command to the worker of the actor and waits until it has
+// been executed, cancelled or superseded.
+func (s *ShadingActor) Apply(command commands.LLCommand) error {
+	_, done, err := s.submit(command)
+	if err != nil {
+		return err
+	}
+	return <-done
+}
+
+// submit tracks the command and queues it unless the actor refuses it. The
+// returned channel delivers the outcome of a queued command, the error is
+// set when the command was refused.
+func (s *ShadingActor) submit(command commands.LLCommand) (commands.LLCommand, <-chan error, error) {
+	Commands.track(s, &command)
+	CommandsReceived.WithLabelValues(string(command.Source)).Inc()
+	if err := s.Check(command); err != nil {
+		s.refuse(command, err)
+		return command, nil, err
+	}
+	return command, s.enqueue(command), nil
+}
+
+// refuse finishes a command the actor does not execute because it is locked
+// or its contact is open
+func (s *ShadingActor) refuse(command commands.LLCommand, err error) {
+	logger.Warn("Rejecting command", command.ID, err)
+	CommandsRejected.WithLabelValues(string(command.Source), RejectedRefused).Inc()
+	Commands.finish(s, command.ID, err)
+	Events.Publish(Event{Type: EventCommandFailed, Actor: s, Command: &command, Error: err})
+}
+
+// execute runs the command on the worker of the actor. ctx is cancelled when
+// a later command supersedes this one.
+func (s *ShadingActor) execute(ctx context.Context, command commands.LLCommand) error {
+	// The lock or the contact may have changed while the command was queued
+	err := s.Check(command)
+	if err != nil {
+		s.refuse(command, err)
+		return err
+	}
+
+	Commands.start(s, command.ID)
+
+	s.mu.Lock()
+	s.lastCommand = &command
+	s.lastCommandSource = command.Source
+	s.mu.Unlock()
 
 	switch command.Action {
 	case commands.LLActionSet:
-		_, err := s.SetPosition(command.Position)
+		_, err = s.SetPosition(command.Position)
 		if err != nil {
 			logger.Error("Failed setting position", err)
 		} else {
 			logger.Info("Set position to", command.Position)
 		}
 	case commands.LLActionTilt:
-		s.Tilt(command.Position)
+		err = s.tilt(ctx, command.Position)
+	case commands.LLActionStop:
+		err = s.StopMovement()
+		if err != nil {
+			logger.Error("Failed stopping movement", err)
+		} else {
+			logger.Info("Stopped movement of", s)
+		}
 	}
+
+	Commands.finish(s, command.ID, err)
+	if err != nil {
+		Events.Publish(Event{Type: EventCommandFailed, Actor: s, Command: &command, Error: err})
+	} else {
+		Events.Publish(Event{Type: EventCommandAccepted, Actor: s, Command: &command})
+	}
+	return err
 }
 
-func (s *ShadingActor) Tilt(position int) {
+// abortMotion cancels the motion sequence that is currently in flight, if any.
+func (s *ShadingActor) abortMotion() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancelMotion != nil {
+		s.cancelMotion(ErrCancelled)
+		s.cancelMotion = nil
+	}
+}
+
+// StopMovement halts the shading. The device has no dedicated stop function,
+// so the target position is set to the position currently reported.
+func (s *ShadingActor) StopMovement() error {
+	logger.Debug("Stop command received", s)
+	s.abortMotion()
+
+	position, err := s.getPosition()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.SetPosition(position)
+	return err
+}
+
+func (s *ShadingActor) tilt(ctx context.Context, position int) error {
 	logger.Debug("Tilt command received", s, "to position", position)
 	if config.Get().Eltako.GetOptimizeTilt() && s.Tilted && s.TiltPosition == position {
 		logger.Debug("Ignoring tilt command, already tilted correctly", s)
-		return
+		return nil
 	}
 
 	wg := sync.WaitGroup{}
@@ -35,16 +132,21 @@ func (s *ShadingActor) Tilt(position int) {
 	startPosition, err := s.getPosition()
 	if err != nil {
 		logger.Error("Tilt failed; error getting position", s, err)
-		return
+		return err
 	}
 
-	err = s.SetAndWaitForPosition(&wg, position, 60)
+	err = s.SetAndWaitForPosition(ctx, &wg, position, 60)
 	if err != nil {
 		logger.Error("Tilt failed; error setting position", s, err)
-		return
+		return err
 	}
 	wg.Wait()
 
+	if ctx.Err() != nil {
+		logger.Debug("Tilt command cancelled", s, context.Cause(ctx))
+		return context.Cause(ctx)
+	}
+
 	offset := 0
 	if startPosition < position {
 		offset = -int(s.Config.TiltDownPercentage)
@@ -52,17 +154,26 @@ func (s *ShadingActor) Tilt(position int) {
 		offset = int(s.Config.TiltUpPercentage)
 	}
 
-	_, err = s.SetPosition(position + offset)
+	err = s.SetAndWaitForPosition(ctx, &wg, position+offset, 60)
 	if err != nil {
 		logger.Error("Tilt failed; error setting tilt position", s, err)
-		return
+		return err
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		logger.Debug("Tilt command cancelled", s, context.Cause(ctx))
+		return context.Cause(ctx)
 	}
 
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	s.Tilted = true
 	s.TiltPosition = position
+	s.mu.Unlock()
+	s.storeState(false)
+
 	logger.Debug("Tilt command executed successfully", s, "to position", position, "with offset", offset)
+	Events.Publish(Event{Type: EventTiltChanged, Actor: s, Position: position, Tilted: true})
+	return nil
 
 }
@@ -2,70 +2,135 @@ package eltako
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/mqtt-home/eltako-to-mqtt-gw/commands"
 	"github.com/mqtt-home/eltako-to-mqtt-gw/config"
 	"github.com/philipparndt/go-logger"
 )
 
+// DefaultModel is used when the model is not announced through Zeroconf
+const DefaultModel = "ESB62NP-IP"
+
 type ShadingActor struct {
-	device       config.Device
-	client       *HTTPClient
-	Devices      []Device
-	Name         string
-	IP           string
-	Serial       string
-	Config       config.BlindsConfig
-	Tilted       bool
-	TiltPosition int
-	Position     int
-	mu           sync.Mutex
+	device            config.Device
+	client            *HTTPClient
+	Devices           []Device
+	Name              string
+	IP                string
+	Serial            string
+	Model             string
+	Config            config.BlindsConfig
+	Tilted            bool
+	TiltPosition      int
+	Position          int
+	mu                sync.Mutex
+	cancelMotion      context.CancelCauseFunc
+	queue             []*job
+	pending           chan struct{}
+	startWorker       sync.Once
+	health            Health
+	failures          int
+	moving            bool
+	target            int
+	lastRead          int
+	stableReads       int
+	direction         Direction
+	lastCommand       *commands.LLCommand
+	lastCommandSource commands.Source
+	wake              chan struct{}
+	updatedAt         time.Time
+	refreshing        atomic.Bool
+	locks             map[string]string
+	contactOpen       bool
+}
+
+// baseURL builds the API URL of the device. The port defaults to 443 unless
+// the address already contains one.
+func baseURL(ip string) string {
+	if _, _, err := net.SplitHostPort(ip); err == nil {
+		return fmt.Sprintf("https://%s/api/v0", ip)
+	}
+	return fmt.Sprintf("https://%s:443/api/v0", ip)
 }
 
 func NewShadingActor(device config.Device) *ShadingActor {
-	client := NewHTTPClient(fmt.Sprintf("https://%s:443/api/v0", device.Ip))
+	client := NewHTTPClient(baseURL(device.Ip))
 	actor := &ShadingActor{
 		device: device,
 		client: client,
 		Name:   device.Name,
 		IP:     device.Ip,
 		Serial: device.Serial,
+		Model:  DefaultModel,
 		Config: device.BlindsConfig,
 		Tilted: false,
+
+		lastRead: unknownTarget,
+		wake:     make(chan struct{}, 1),
+		pending:  make(chan struct{}, 1),
+		locks:    make(map[string]string),
 	}
-	err := actor.init()
-	if err != nil {
-		panic(err)
-	}
+	actor.restore()
 	return actor
 }
 
+// init logs in and enumerates the devices of the actor. It may fail when the
+// device is not reachable yet and is retried until it succeeds.
 func (s *ShadingActor) init() error {
 	err := s.UpdateToken()
 	if err != nil {
 		return err
 	}
 	devices, err := s.getDevices()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
 	s.Devices = devices
+	s.mu.Unlock()
 	return nil
 }
 
+func (s *ShadingActor) IsInitialized() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.Devices) > 0
+}
+
 func (s *ShadingActor) DisplayName() string {
 	if s.Name == "" {
 		info, err := s.findDeviceByInfo("currentPosition")
 		if err != nil {
-			return s.IP
+			return s.Address()
 		}
 		return info.DisplayName
 	}
 	return s.Name
 }
 
+// UpdateToken logs in to the device and replaces the token of the client
 func (s *ShadingActor) UpdateToken() error {
+	err := s.login()
+	if err != nil {
+		tokenRefreshes.WithLabelValues(s.Name, "failure").Inc()
+	} else {
+		tokenRefreshes.WithLabelValues(s.Name, "success").Inc()
+	}
+	return err
+}
+
+func (s *ShadingActor) login() error {
 	usernamePassword := map[string]string{
 		"user":     s.device.Username,
 		"password": s.device.Password,
@@ -85,7 +150,7 @@ func (s *ShadingActor) UpdateToken() error {
 	}(resp.Body)
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to update token, status code: %d", resp.StatusCode)
+		return &StatusError{Operation: "update token", StatusCode: resp.StatusCode}
 	}
 
 	var result map[string]string
@@ -112,7 +177,7 @@ func (s *ShadingActor) getDevices() ([]Device, error) {
 	}(resp.Body)
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get devices, status code: %d", resp.StatusCode)
+		return nil, &StatusError{Operation: "get devices", StatusCode: resp.StatusCode}
 	}
 
 	var devices []Device
@@ -123,8 +188,22 @@ func (s *ShadingActor) getDevices() ([]Device, error) {
 	return devices, nil
 }
 
+func (s *ShadingActor) devices() ([]Device, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.Devices) == 0 {
+		return nil, fmt.Errorf("actor %s is not initialized yet", s.Name)
+	}
+	return s.Devices, nil
+}
+
 func (s *ShadingActor) findDeviceByFunctionName(functionName string) (*Device, error) {
-	for _, device := range s.Devices {
+	devices, err := s.devices()
+	if err != nil {
+		return nil, err
+	}
+	for _, device := range devices {
 		for _, function := range device.Functions {
 			if function.Identifier == functionName {
 				return &device, nil
@@ -134,7 +213,11 @@ func (s *ShadingActor) findDeviceByFunctionName(functionName string) (*Device, e
 	return nil, fmt.Errorf("device not found")
 }
 func (s *ShadingActor) findDeviceByInfo(infoName string) (*Device, error) {
-	for _, device := range s.Devices {
+	devices, err := s.devices()
+	if err != nil {
+		return nil, err
+	}
+	for _, device := range devices {
 		for _, info := range device.Infos {
 			if info.Identifier == infoName {
 				return &device, nil
@@ -144,25 +227,85 @@ func (s *ShadingActor) findDeviceByInfo(infoName string) (*Device, error) {
 	return nil, fmt.Errorf("device not found")
 }
 
-func (s *ShadingActor) String() string {
-	return fmt.Sprintf("ShadingActor{name: %s; ip: %s}", s.Name, s.IP)
+func (s *ShadingActor) Address() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.IP
 }
 
-func (s *ShadingActor) Start(wg *sync.WaitGroup, pollingInterval int) error {
-	wg.Add(2)
+// SetAddress re-points the actor to a new IP address, e.g. after the DHCP
+// server handed out a new lease, and logs in again.
+func (s *ShadingActor) SetAddress(ip string) {
+	s.mu.Lock()
+	changed := s.IP != ip
+	s.IP = ip
+	s.device.Ip = ip
+	s.mu.Unlock()
+
+	if changed {
+		logger.Info(fmt.Sprintf("Address of %s changed", s), ip)
+		s.client.SetBaseURL(baseURL(ip))
+	}
+
+	if !s.IsInitialized() {
+		// The background initialization picks up the new address
+		return
+	}
+
 	err := s.UpdateToken()
 	if err != nil {
-		logger.Error(fmt.Sprintf("Initial token update failed for %s", s), err)
-		return err
+		logger.Error(fmt.Sprintf("Re-login of %s failed", s), err)
+		s.setHealth(HealthOffline)
+		return
 	}
+	s.recordSuccess()
+}
 
-	go s.scheduleUpdateToken(wg)
-
-	if pollingInterval > 0 {
-		go s.schedulePolling(wg, pollingInterval)
-	} else {
-		logger.Info(fmt.Sprintf("Polling disabled for %s", s))
+// MarkOffline is used when the device is no longer announced on the network.
+// Polling keeps trying to reconnect with back-off.
+func (s *ShadingActor) MarkOffline() {
+	if !s.IsInitialized() {
+		return
 	}
+	s.setHealth(HealthOffline)
+}
 
-	return nil
+func (s *ShadingActor) String() string {
+	return fmt.Sprintf("ShadingActor{name: %s; ip: %s}", s.Name, s.Address())
+}
+
+// Start initializes the actor in the background. The actor stays pending
+// until the device answers; token refresh and polling start afterwards.
+func (s *ShadingActor) Start() {
+	s.setHealth(HealthPending)
+
+	go func() {
+		s.waitForInit()
+
+		go s.scheduleUpdateToken()
+
+		if s.device.PollingInterval > 0 {
+			s.schedulePolling(s.device.PollingInterval, s.device.FastPollingInterval)
+		} else {
+			logger.Info(fmt.Sprintf("Polling disabled for %s", s))
+		}
+	}()
+}
+
+func (s *ShadingActor) waitForInit() {
+	failures := 0
+	for {
+		err := s.init()
+		if err == nil {
+			logger.Info(fmt.Sprintf("Initialized %s", s))
+			s.recordSuccess()
+			return
+		}
+
+		failures++
+		delay := backoff(failures)
+		logger.Warn(fmt.Sprintf("Initializing %s failed, retrying in %s", s, delay), err)
+		time.Sleep(delay)
+	}
 }
@@ -0,0 +1,132 @@
+package eltako
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/commands"
+	"github.com/philipparndt/go-logger"
+)
+
+type EventType string
+
+const (
+	EventPositionChanged  EventType = "positionChanged"
+	EventMovementStarted  EventType = "movementStarted"
+	EventMovementStopped  EventType = "movementStopped"
+	EventTiltChanged      EventType = "tiltChanged"
+	EventHealthChanged    EventType = "healthChanged"
+	EventActorOnline      EventType = "actorOnline"
+	EventActorOffline     EventType = "actorOffline"
+	EventCommandAccepted  EventType = "commandAccepted"
+	EventCommandFailed    EventType = "commandFailed"
+	EventLockChanged      EventType = "lockChanged"
+	EventInterlockChanged EventType = "interlockChanged"
+	EventCommandChanged   EventType = "commandChanged"
+)
+
+// Event describes a change of an actor. Depending on the type only some of
+// the fields are set.
+type Event struct {
+	Type     EventType
+	Actor    *ShadingActor
+	Time     time.Time
+	Position int
+	Tilted   bool
+	Health   Health
+	Command  *commands.LLCommand
+	Record   *CommandRecord
+	Error    error
+}
+
+// Subscription receives the events of an EventBus. When the subscriber does
+// not keep up, the oldest queued events are dropped so a slow consumer never
+// blocks the publisher.
+type Subscription struct {
+	C       <-chan Event
+	ch      chan Event
+	name    string
+	bus     *EventBus
+	mu      sync.Mutex
+	dropped int
+}
+
+type EventBus struct {
+	subscribers map[*Subscription]struct{}
+	mu          sync.RWMutex
+}
+
+// Events is the bus all actors publish to
+var Events = NewEventBus()
+
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+func (b *EventBus) Subscribe(name string, queueSize int) *Subscription {
+	ch := make(chan Event, queueSize)
+	sub := &Subscription{
+		C:    ch,
+		ch:   ch,
+		name: name,
+		bus:  b,
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscribers[sub] = struct{}{}
+	logger.Debug("Event bus subscriber added", name)
+	return sub
+}
+
+func (b *EventBus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subscribers {
+		sub.offer(event)
+	}
+}
+
+func (s *Subscription) offer(event Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		select {
+		case s.ch <- event:
+			return
+		default:
+		}
+
+		// Queue is full, drop the oldest event
+		select {
+		case <-s.ch:
+			s.dropped++
+			if s.dropped == 1 || s.dropped%100 == 0 {
+				logger.Warn("Event bus subscriber is too slow, dropping events", s.name, s.dropped)
+			}
+		default:
+		}
+	}
+}
+
+// Close removes the subscription from the bus and closes its channel
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	delete(s.bus.subscribers, s)
+	s.bus.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	close(s.ch)
+	logger.Debug("Event bus subscriber removed", s.name)
+}
@@ -0,0 +1,283 @@
+// Package fake provides an in-process simulator of the ESB62NP-IP REST API
+// for integration tests.
+package fake
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DeviceGuid = "00000000-0000-0000-0000-000000000001"
+	Username   = "admin"
+	Password   = "123456789"
+)
+
+// Operation identifies an endpoint for failure injection
+type Operation string
+
+const (
+	OpLogin       Operation = "login"
+	OpDevices     Operation = "devices"
+	OpGetPosition Operation = "getPosition"
+	OpSetPosition Operation = "setPosition"
+)
+
+type failure struct {
+	status int
+	count  int
+}
+
+// Server simulates a single shading actor. The position moves linearly
+// towards the target, a full travel from 0 to 100 takes TravelTime.
+type Server struct {
+	*httptest.Server
+
+	TravelTime time.Duration
+	TokenTTL   time.Duration
+
+	mu          sync.Mutex
+	tokens      map[string]time.Time
+	startPos    float64
+	target      float64
+	moveStart   time.Time
+	failures    map[Operation]*failure
+	requests    map[Operation]int
+	targetsSent []int
+}
+
+// NewServer starts a TLS server with the shading at the given position
+func NewServer(position int) *Server {
+	s := &Server{
+		TravelTime: time.Second,
+		TokenTTL:   time.Hour,
+		tokens:     make(map[string]time.Time),
+		startPos:   float64(position),
+		target:     float64(position),
+		moveStart:  time.Now(),
+		failures:   make(map[Operation]*failure),
+		requests:   make(map[Operation]int),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v0/login", s.handleLogin)
+	mux.HandleFunc("GET /api/v0/devices", s.authorized(OpDevices, s.handleDevices))
+	mux.HandleFunc("GET /api/v0/devices/{guid}/infos/currentPosition", s.authorized(OpGetPosition, s.handleGetPosition))
+	mux.HandleFunc("PUT /api/v0/devices/{guid}/functions/targetPosition", s.authorized(OpSetPosition, s.handleSetPosition))
+
+	s.Server = httptest.NewTLSServer(mux)
+	return s
+}
+
+// Address returns host:port of the server, usable as device IP
+func (s *Server) Address() string {
+	return strings.TrimPrefix(s.URL, "https://")
+}
+
+// currentPosition must be called with the lock held
+func (s *Server) currentPosition() float64 {
+	if s.TravelTime <= 0 {
+		return s.target
+	}
+
+	distance := s.target - s.startPos
+	travelled := 100 * time.Since(s.moveStart).Seconds() / s.TravelTime.Seconds()
+	if travelled >= math.Abs(distance) {
+		return s.target
+	}
+	return s.startPos + math.Copysign(travelled, distance)
+}
+
+// Position returns the simulated position
+func (s *Server) Position() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return int(math.Round(s.currentPosition()))
+}
+
+// Target returns the target position the shading moves to
+func (s *Server) Target() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return int(s.target)
+}
+
+// TargetsSent returns all target positions received through the API
+func (s *Server) TargetsSent() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]int(nil), s.targetsSent...)
+}
+
+// Requests returns the number of requests received for the operation
+func (s *Server) Requests(op Operation) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests[op]
+}
+
+// MoveTo moves the shading as if a wall switch was pressed
+func (s *Server) MoveTo(position int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.moveTo(float64(position))
+}
+
+func (s *Server) moveTo(position float64) {
+	s.startPos = s.currentPosition()
+	s.target = position
+	s.moveStart = time.Now()
+}
+
+// Fail lets the next count requests of the operation fail with the status code
+func (s *Server) Fail(op Operation, status int, count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[op] = &failure{status: status, count: count}
+}
+
+// ExpireTokens invalidates all issued tokens
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens = make(map[string]time.Time)
+}
+
+// injectedFailure returns the status code of an injected failure or 0
+func (s *Server) injectedFailure(op Operation) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests[op]++
+	f, ok := s.failures[op]
+	if !ok || f.count <= 0 {
+		return 0
+	}
+	f.count--
+	return f.status
+}
+
+func (s *Server) authorized(op Operation, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if status := s.injectedFailure(op); status != 0 {
+			w.WriteHeader(status)
+			return
+		}
+
+		s.mu.Lock()
+		issued, ok := s.tokens[r.Header.Get("Authorization")]
+		valid := ok && time.Since(issued) < s.TokenTTL
+		s.mu.Unlock()
+
+		if !valid {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if status := s.injectedFailure(OpLogin); status != 0 {
+		w.WriteHeader(status)
+		return
+	}
+
+	var credentials map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if credentials["user"] != Username || credentials["password"] != Password {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	token := hex.EncodeToString(buf)
+
+	s.mu.Lock()
+	s.tokens[token] = time.Now()
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]string{"apiKey": token})
+}
+
+func (s *Server) handleDevices(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, []map[string]any{
+		{
+			"deviceGuid":  DeviceGuid,
+			"productGuid": "00000000-0000-0000-0000-000000000000",
+			"displayName": "Fake",
+			"infos": []map[string]any{
+				{"type": "number", "identifier": "currentPosition", "value": s.Position()},
+			},
+			"settings": []map[string]any{},
+			"functions": []map[string]any{
+				{"type": "number", "identifier": "targetPosition", "value": s.Target()},
+			},
+		},
+	})
+}
+
+func (s *Server) handleGetPosition(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("guid") != DeviceGuid {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"type":       "number",
+		"identifier": "currentPosition",
+		"value":      s.Position(),
+	})
+}
+
+func (s *Server) handleSetPosition(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("guid") != DeviceGuid {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var command struct {
+		Value float64 `json:"value"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&command); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if command.Value < 0 || command.Value > 100 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.moveTo(command.Value)
+	s.targetsSent = append(s.targetsSent, int(command.Value))
+	s.mu.Unlock()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func writeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(data)
+}
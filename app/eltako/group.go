@@ -0,0 +1,104 @@
+package eltako
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/commands"
+	"github.com/philipparndt/go-logger"
+)
+
+// ActorGroup is a named set of actors. Members are resolved by name on every
+// use as actors discovered through Zeroconf may register late.
+type ActorGroup struct {
+	Name     string
+	Members  []string
+	registry *ActorRegistry
+}
+
+type GroupState struct {
+	Name        string `json:"name"`
+	Actors      int    `json:"actors"`
+	MinPosition int    `json:"minPosition"`
+	MaxPosition int    `json:"maxPosition"`
+	AvgPosition int    `json:"avgPosition"`
+	Moving      bool   `json:"moving"`
+}
+
+func NewActorGroup(name string, members []string, registry *ActorRegistry) *ActorGroup {
+	return &ActorGroup{
+		Name:     name,
+		Members:  members,
+		registry: registry,
+	}
+}
+
+// Actors returns the registered members of the group
+func (g *ActorGroup) Actors() []*ShadingActor {
+	var actors []*ShadingActor
+	for _, member := range g.Members {
+		actor := g.registry.GetActor(member)
+		if actor == nil {
+			logger.Debug("Group member not registered (yet)", g.Name, member)
+			continue
+		}
+		actors = append(actors, actor)
+	}
+	return actors
+}
+
+func (g *ActorGroup) Contains(actor *ShadingActor) bool {
+	for _, member := range g.Members {
+		if strings.EqualFold(member, actor.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply executes the command on all members concurrently and waits until
+// all of them are done. The error joins the errors of the failed members.
+func (g *ActorGroup) Apply(command commands.LLCommand) error {
+	actors := g.Actors()
+	logger.Info("Applying command to group", g.Name, command.Action, len(actors))
+
+	errs := make([]error, len(actors))
+	wg := sync.WaitGroup{}
+	for i, actor := range actors {
+		wg.Add(1)
+		go func(i int, actor *ShadingActor) {
+			defer wg.Done()
+			if err := actor.Apply(command); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", actor.Name, err)
+			}
+		}(i, actor)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// State aggregates the cached states of the members
+func (g *ActorGroup) State() GroupState {
+	state := GroupState{Name: g.Name}
+
+	sum := 0
+	for _, actor := range g.Actors() {
+		actorState := actor.CachedState()
+		if state.Actors == 0 || actorState.Position < state.MinPosition {
+			state.MinPosition = actorState.Position
+		}
+		if state.Actors == 0 || actorState.Position > state.MaxPosition {
+			state.MaxPosition = actorState.Position
+		}
+		sum += actorState.Position
+		state.Moving = state.Moving || actorState.Moving
+		state.Actors++
+	}
+
+	if state.Actors > 0 {
+		state.AvgPosition = (sum + state.Actors/2) / state.Actors
+	}
+	return state
+}
@@ -0,0 +1,74 @@
+package eltako
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/commands"
+)
+
+func TestGroupAppliesCommandToAllMembers(t *testing.T) {
+	registry := NewActorRegistry()
+	first, firstServer := newTestActor(t, 100)
+	second, secondServer := newTestActor(t, 50)
+	first.Name, second.Name = t.Name()+"-first", t.Name()+"-second"
+	registry.AddActor(first)
+	registry.AddActor(second)
+
+	group := NewActorGroup("south", []string{first.Name, second.Name, "not-registered"}, registry)
+	registry.AddGroup(group)
+
+	if err := group.Apply(commands.LLCommand{Action: commands.LLActionSet, Position: 20}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	if firstServer.Target() != 20 || secondServer.Target() != 20 {
+		t.Errorf("expected both members to move to 20, got %d and %d", firstServer.Target(), secondServer.Target())
+	}
+	if groups := registry.GroupsOf(first); len(groups) != 1 || groups[0] != group {
+		t.Errorf("expected actor to be member of the group, got %v", groups)
+	}
+}
+
+func TestGroupApplyReportsFailedMembers(t *testing.T) {
+	registry := NewActorRegistry()
+	first, firstServer := newTestActor(t, 100)
+	second, _ := newTestActor(t, 50)
+	first.Name, second.Name = t.Name()+"-first", t.Name()+"-second"
+	registry.AddActor(first)
+	registry.AddActor(second)
+	second.Lock("wind", "wind speed above threshold")
+
+	group := NewActorGroup("south", []string{first.Name, second.Name}, registry)
+	err := group.Apply(commands.LLCommand{Action: commands.LLActionSet, Position: 20})
+
+	var locked *LockedError
+	if !errors.As(err, &locked) || !strings.Contains(err.Error(), second.Name) {
+		t.Errorf("expected the locked member to be reported, got %v", err)
+	}
+	if firstServer.Target() != 20 {
+		t.Errorf("expected the other member to move to 20, got %d", firstServer.Target())
+	}
+}
+
+func TestGroupState(t *testing.T) {
+	registry := NewActorRegistry()
+	first, _ := newTestActor(t, 100)
+	second, _ := newTestActor(t, 25)
+	first.Name, second.Name = t.Name()+"-first", t.Name()+"-second"
+	registry.AddActor(first)
+	registry.AddActor(second)
+	for _, actor := range []*ShadingActor{first, second} {
+		if _, err := actor.getPosition(); err != nil {
+			t.Fatalf("getPosition failed: %v", err)
+		}
+	}
+
+	state := NewActorGroup("south", []string{first.Name, second.Name}, registry).State()
+
+	expected := GroupState{Name: "south", Actors: 2, MinPosition: 25, MaxPosition: 100, AvgPosition: 63}
+	if state != expected {
+		t.Errorf("expected %+v, got %+v", expected, state)
+	}
+}
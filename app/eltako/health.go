@@ -0,0 +1,162 @@
+package eltako
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/config"
+	"github.com/philipparndt/go-logger"
+)
+
+type Health string
+
+const (
+	HealthPending          Health = "pending"
+	HealthOnline           Health = "online"
+	HealthDegraded         Health = "degraded"
+	HealthOffline          Health = "offline"
+	HealthReauthenticating Health = "reauthenticating"
+)
+
+const (
+	AvailabilityOnline  = "online"
+	AvailabilityOffline = "offline"
+)
+
+// maxConsecutiveFailures is the number of failed requests after which
+// a degraded actor is reported as offline
+const maxConsecutiveFailures = 3
+
+const (
+	initialBackoff = 5 * time.Second
+	maxBackoff     = 5 * time.Minute
+)
+
+// StatusError is returned when the device answers with an unexpected status code
+type StatusError struct {
+	Operation  string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("failed to %s, status code: %d", e.Operation, e.StatusCode)
+}
+
+func isUnauthorized(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusUnauthorized
+}
+
+func (h Health) Available() bool {
+	return h == HealthOnline || h == HealthDegraded
+}
+
+func (s *ShadingActor) AvailabilityTopic() string {
+	return config.Get().MQTT.Topic + "/" + s.DisplayName() + "/availability"
+}
+
+func (s *ShadingActor) HealthTopic() string {
+	return config.Get().MQTT.Topic + "/" + s.DisplayName() + "/health"
+}
+
+func (s *ShadingActor) Health() Health {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.health
+}
+
+func (s *ShadingActor) IsAvailable() bool {
+	return s.Health().Available()
+}
+
+func (s *ShadingActor) setHealth(health Health) {
+	s.mu.Lock()
+	old := s.health
+	s.health = health
+	s.mu.Unlock()
+
+	if old == health {
+		return
+	}
+	s.storeState(false)
+
+	if health == HealthOnline {
+		logger.Info("Actor is online", s)
+	} else {
+		logger.Warn("Actor health changed", s, "from", old, "to", health)
+	}
+	Events.Publish(Event{Type: EventHealthChanged, Actor: s, Health: health})
+
+	if old == "" || old.Available() != health.Available() {
+		eventType := EventActorOffline
+		if health.Available() {
+			eventType = EventActorOnline
+		}
+		Events.Publish(Event{Type: eventType, Actor: s, Health: health})
+	}
+}
+
+func (s *ShadingActor) recordSuccess() {
+	s.mu.Lock()
+	s.failures = 0
+	s.mu.Unlock()
+
+	s.setHealth(HealthOnline)
+}
+
+func (s *ShadingActor) recordFailure(err error) {
+	s.mu.Lock()
+	s.failures++
+	failures := s.failures
+	pending := s.health == HealthPending
+	s.mu.Unlock()
+
+	logger.Debug("Request to actor failed", s, failures, err)
+	if pending {
+		// Initialization is retried in the background
+		return
+	}
+
+	if isUnauthorized(err) {
+		s.reauthenticate()
+		return
+	}
+
+	if failures >= maxConsecutiveFailures {
+		s.setHealth(HealthOffline)
+	} else {
+		s.setHealth(HealthDegraded)
+	}
+}
+
+// reauthenticate requests a new token, e.g. after the device rebooted and
+// rejected the current one
+func (s *ShadingActor) reauthenticate() {
+	s.setHealth(HealthReauthenticating)
+
+	err := s.UpdateToken()
+	if err != nil {
+		logger.Error("Re-login failed", s, err)
+		s.setHealth(HealthOffline)
+		return
+	}
+
+	logger.Info("Re-login succeeded", s)
+	s.setHealth(HealthDegraded)
+}
+
+// backoff returns the delay before the next attempt after the given number
+// of consecutive failures
+func backoff(failures int) time.Duration {
+	delay := initialBackoff
+	for i := 1; i < failures && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
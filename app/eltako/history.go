@@ -0,0 +1,193 @@
+package eltako
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/commands"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/storage"
+	"github.com/philipparndt/go-logger"
+)
+
+type HistoryKind string
+
+const (
+	HistoryPosition HistoryKind = "position"
+	HistoryCommand  HistoryKind = "command"
+)
+
+// historyFlushInterval is the interval in which new entries are written to disk
+const historyFlushInterval = time.Minute
+
+// HistoryEntry is a position change or a finished command of an actor
+type HistoryEntry struct {
+	Time     time.Time       `json:"time"`
+	Kind     HistoryKind     `json:"kind"`
+	Position int             `json:"position"`
+	Tilted   bool            `json:"tilted,omitempty"`
+	Source   commands.Source `json:"source,omitempty"`
+	// The fields below are only set for commands
+	Action    commands.LLAction `json:"action,omitempty"`
+	CommandID string            `json:"commandId,omitempty"`
+	Status    CommandStatus     `json:"status,omitempty"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// HistoryStore keeps the most recent entries of each actor in memory and
+// writes them to a JSON file from time to time.
+type HistoryStore struct {
+	file    string
+	limit   int
+	entries map[string][]HistoryEntry
+	dirty   bool
+	mu      sync.Mutex
+}
+
+// History records the position changes and commands of all actors. It is
+// kept in memory only until replaced by a store opened from a file.
+var History = NewHistoryStore("", 5000)
+
+// NewHistoryStore creates an empty store keeping limit entries per actor.
+// Entries are not written to disk when file is empty.
+func NewHistoryStore(file string, limit int) *HistoryStore {
+	return &HistoryStore{
+		file:    file,
+		limit:   limit,
+		entries: make(map[string][]HistoryEntry),
+	}
+}
+
+// OpenHistoryStore reads the history stored in the file. A missing file
+// results in an empty store.
+func OpenHistoryStore(file string, limit int) (*HistoryStore, error) {
+	h := NewHistoryStore(file, limit)
+	if err := storage.ReadJSON(file, &h.entries); err != nil {
+		return nil, fmt.Errorf("failed to read history from %s: %w", file, err)
+	}
+	for name, entries := range h.entries {
+		h.entries[name] = h.trim(entries)
+	}
+	return h, nil
+}
+
+func (h *HistoryStore) trim(entries []HistoryEntry) []HistoryEntry {
+	if len(entries) > h.limit {
+		return entries[len(entries)-h.limit:]
+	}
+	return entries
+}
+
+// Record adds an entry to the history of the actor
+func (h *HistoryStore) Record(actor string, entry HistoryEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := strings.ToLower(actor)
+	entries := append(h.entries[key], entry)
+
+	// Entries arrive almost always in order, keep it that way for Range
+	for i := len(entries) - 1; i > 0 && entries[i].Time.Before(entries[i-1].Time); i-- {
+		entries[i], entries[i-1] = entries[i-1], entries[i]
+	}
+
+	h.entries[key] = h.trim(entries)
+	h.dirty = true
+}
+
+// Range returns the entries of the actor recorded between from and to,
+// oldest first. A zero time leaves the range open on that side.
+func (h *HistoryStore) Range(actor string, from time.Time, to time.Time) []HistoryEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entries := h.entries[strings.ToLower(actor)]
+	start := 0
+	if !from.IsZero() {
+		start = sort.Search(len(entries), func(i int) bool {
+			return !entries[i].Time.Before(from)
+		})
+	}
+	end := len(entries)
+	if !to.IsZero() {
+		end = sort.Search(len(entries), func(i int) bool {
+			return entries[i].Time.After(to)
+		})
+	}
+	if start >= end {
+		return []HistoryEntry{}
+	}
+	return append([]HistoryEntry{}, entries[start:end]...)
+}
+
+// Flush writes the history to disk if it changed since the last flush
+func (h *HistoryStore) Flush() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.file == "" || !h.dirty {
+		return
+	}
+	if err := storage.WriteJSON(h.file, h.entries); err != nil {
+		logger.Error("Failed to save history", err)
+		return
+	}
+	h.dirty = false
+}
+
+// Start records the events of the bus and flushes the history periodically
+func (h *HistoryStore) Start(bus *EventBus) {
+	sub := bus.Subscribe("history", 1000)
+
+	go func() {
+		for event := range sub.C {
+			if entry, ok := historyEntryOf(event); ok {
+				h.Record(event.Actor.Name, entry)
+			}
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(historyFlushInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			h.Flush()
+		}
+	}()
+}
+
+func historyEntryOf(event Event) (HistoryEntry, bool) {
+	switch event.Type {
+	case EventPositionChanged:
+		state := event.Actor.CachedState()
+		source := state.LastCommandSource
+		if source == "" {
+			source = commands.SourceExternal
+		}
+		return HistoryEntry{
+			Time:     event.Time,
+			Kind:     HistoryPosition,
+			Position: event.Position,
+			Tilted:   state.Tilted,
+			Source:   source,
+		}, true
+	case EventCommandChanged:
+		record := event.Record
+		if !record.Status.IsFinished() {
+			return HistoryEntry{}, false
+		}
+		return HistoryEntry{
+			Time:      record.CreatedAt,
+			Kind:      HistoryCommand,
+			Position:  record.Position,
+			Source:    record.Source,
+			Action:    record.Action,
+			CommandID: record.ID,
+			Status:    record.Status,
+			Error:     record.Error,
+		}, true
+	}
+	return HistoryEntry{}, false
+}
@@ -0,0 +1,50 @@
+package eltako
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistoryRangeAndLimit(t *testing.T) {
+	history := NewHistoryStore("", 3)
+	start := time.Date(2026, 6, 1, 7, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		history.Record("Living", HistoryEntry{Time: start.Add(time.Duration(i) * time.Minute), Kind: HistoryPosition, Position: i})
+	}
+	// An entry arriving late is sorted in
+	history.Record("living", HistoryEntry{Time: start.Add(150 * time.Second), Kind: HistoryPosition, Position: 99})
+
+	entries := history.Range("living", time.Time{}, time.Time{})
+	positions := []int{}
+	for _, entry := range entries {
+		positions = append(positions, entry.Position)
+	}
+	if len(positions) != 3 || positions[0] != 99 || positions[1] != 3 || positions[2] != 4 {
+		t.Errorf("expected the newest 3 entries in order, got %v", positions)
+	}
+
+	entries = history.Range("living", start.Add(3*time.Minute), start.Add(3*time.Minute))
+	if len(entries) != 1 || entries[0].Position != 3 {
+		t.Errorf("expected only the entry at 07:03, got %+v", entries)
+	}
+	if entries := history.Range("unknown", time.Time{}, time.Time{}); len(entries) != 0 {
+		t.Errorf("expected no entries, got %+v", entries)
+	}
+}
+
+func TestHistoryFlush(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history.json")
+	history := NewHistoryStore(file, 10)
+	history.Record("living", HistoryEntry{Time: time.Now(), Kind: HistoryCommand, Position: 50, CommandID: "1"})
+	history.Flush()
+
+	restored, err := OpenHistoryStore(file, 10)
+	if err != nil {
+		t.Fatalf("OpenHistoryStore failed: %v", err)
+	}
+	if entries := restored.Range("living", time.Time{}, time.Time{}); len(entries) != 1 || entries[0].CommandID != "1" {
+		t.Errorf("expected the flushed entry, got %+v", entries)
+	}
+}
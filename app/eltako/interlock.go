@@ -0,0 +1,134 @@
+package eltako
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/commands"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/config"
+	"github.com/philipparndt/go-logger"
+)
+
+// InterlockError is returned by Apply when the command is refused because
+// the window or door contact of the actor is open
+type InterlockError struct {
+	Actor  string
+	Reason string
+}
+
+func (e *InterlockError) Error() string {
+	return fmt.Sprintf("actor %s refused the command: %s", e.Actor, e.Reason)
+}
+
+// IsRefused checks whether the command was refused by the gateway and never
+// reached the device
+func IsRefused(err error) bool {
+	var locked *LockedError
+	var interlock *InterlockError
+	return errors.As(err, &locked) || errors.As(err, &interlock)
+}
+
+type InterlockState struct {
+	ContactOpen bool `json:"contactOpen"`
+	MinPosition *int `json:"minPosition,omitempty"`
+}
+
+// Interlock returns the state of the contact, or nil if the actor has no interlock
+func (s *ShadingActor) Interlock() *InterlockState {
+	if s.device.Interlock == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return &InterlockState{
+		ContactOpen: s.contactOpen,
+		MinPosition: s.device.Interlock.MinPosition,
+	}
+}
+
+// SetContactOpen updates the state of the contact. When the contact opens
+// while the shading moves below the minimum position, it is stopped.
+func (s *ShadingActor) SetContactOpen(open bool) {
+	interlock := s.device.Interlock
+	if interlock == nil {
+		return
+	}
+
+	s.mu.Lock()
+	changed := s.contactOpen != open
+	s.contactOpen = open
+	closing := s.moving && s.direction == DirectionClosing
+	belowMin := s.target == unknownTarget || interlock.MinPosition == nil || s.target < *interlock.MinPosition
+	s.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	logger.Info("Interlock contact changed", s, "open:", open)
+	Events.Publish(Event{Type: EventInterlockChanged, Actor: s})
+
+	if open && closing && belowMin {
+		logger.Warn("Contact opened while closing, stopping", s)
+		go func() {
+			err := s.Apply(commands.LLCommand{Action: commands.LLActionStop, Source: commands.SourceProtection})
+			if err != nil {
+				logger.Error("Failed to stop after the contact opened", s, err)
+			}
+		}()
+	}
+}
+
+// lowestPosition returns the lowest position the command drives the shading
+// to. A tilt may end below its target by the tilt down offset.
+func (s *ShadingActor) lowestPosition(command commands.LLCommand) int {
+	if command.Action == commands.LLActionTilt {
+		return command.Position - int(s.Config.TiltDownPercentage)
+	}
+	return command.Position
+}
+
+// checkInterlock refuses commands that would move the shading below the
+// minimum position while the contact is open
+func (s *ShadingActor) checkInterlock(command commands.LLCommand) error {
+	interlock := s.device.Interlock
+	if interlock == nil || command.Action == commands.LLActionStop {
+		return nil
+	}
+
+	s.mu.Lock()
+	open := s.contactOpen
+	s.mu.Unlock()
+
+	if !open {
+		return nil
+	}
+	if interlock.MinPosition == nil {
+		return &InterlockError{Actor: s.Name, Reason: "contact is open"}
+	}
+	if lowest := s.lowestPosition(command); lowest < *interlock.MinPosition {
+		return &InterlockError{
+			Actor:  s.Name,
+			Reason: fmt.Sprintf("contact is open, position %d is below the minimum of %d", lowest, *interlock.MinPosition),
+		}
+	}
+	return nil
+}
+
+// Check tells whether the command would be refused because the actor is
+// locked or its contact is open
+func (s *ShadingActor) Check(command commands.LLCommand) error {
+	if lock := s.LockState(); lock.Locked && command.Source != commands.SourceProtection {
+		return &LockedError{Actor: s.Name, Reasons: lock.Reasons}
+	}
+	return s.checkInterlock(command)
+}
+
+func (s *ShadingActor) RefusedTopic() string {
+	return config.Get().MQTT.Topic + "/" + s.DisplayName() + "/refused"
+}
+
+func (s *ShadingActor) InterlockTopic() string {
+	return s.DisplayName() + "/interlock"
+}
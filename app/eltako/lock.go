@@ -0,0 +1,75 @@
+package eltako
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/philipparndt/go-logger"
+)
+
+// LockState tells whether commands to an actor are rejected and why
+type LockState struct {
+	Locked  bool     `json:"locked"`
+	Reasons []string `json:"reasons"`
+}
+
+// LockedError is returned by Apply while the actor is locked
+type LockedError struct {
+	Actor   string
+	Reasons []string
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("actor %s is locked: %s", e.Actor, strings.Join(e.Reasons, "; "))
+}
+
+// Lock rejects all commands that are not issued by the protection itself
+// until every owner released its lock. Locking again with the same owner
+// only updates the reason.
+func (s *ShadingActor) Lock(owner string, reason string) {
+	s.mu.Lock()
+	previous, ok := s.locks[owner]
+	s.locks[owner] = reason
+	s.mu.Unlock()
+
+	if ok && previous == reason {
+		return
+	}
+	logger.Warn("Actor locked", s, reason)
+	Events.Publish(Event{Type: EventLockChanged, Actor: s})
+}
+
+func (s *ShadingActor) Unlock(owner string) {
+	s.mu.Lock()
+	_, ok := s.locks[owner]
+	delete(s.locks, owner)
+	s.mu.Unlock()
+
+	if !ok {
+		return
+	}
+	logger.Info("Actor lock released", s, owner)
+	Events.Publish(Event{Type: EventLockChanged, Actor: s})
+}
+
+func (s *ShadingActor) LockState() LockState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owners := make([]string, 0, len(s.locks))
+	for owner := range s.locks {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+
+	state := LockState{Locked: len(owners) > 0, Reasons: []string{}}
+	for _, owner := range owners {
+		state.Reasons = append(state.Reasons, s.locks[owner])
+	}
+	return state
+}
+
+func (s *ShadingActor) LockTopic() string {
+	return s.DisplayName() + "/lock"
+}
@@ -1,5 +1,43 @@
 package eltako
 
+import (
+	"time"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/commands"
+)
+
+type Direction string
+
+const (
+	DirectionOpening Direction = "opening"
+	DirectionClosing Direction = "closing"
+	DirectionStopped Direction = "stopped"
+)
+
 type PositionMessage struct {
-	Position int `json:"position"`
+	Position          int             `json:"position"`
+	Moving            bool            `json:"moving"`
+	Direction         Direction       `json:"direction"`
+	TargetPosition    *int            `json:"targetPosition,omitempty"`
+	LastCommandSource commands.Source `json:"lastCommandSource,omitempty"`
+}
+
+func NewPositionMessage(state ActorState) PositionMessage {
+	return PositionMessage{
+		Position:          state.Position,
+		Moving:            state.Moving,
+		Direction:         state.Direction,
+		TargetPosition:    state.TargetPosition,
+		LastCommandSource: state.LastCommandSource,
+	}
+}
+
+// RefusedMessage is published when a command is refused because the actor
+// is locked or its contact is open
+type RefusedMessage struct {
+	Action   commands.LLAction `json:"action"`
+	Position int               `json:"position"`
+	Source   commands.Source   `json:"source"`
+	Reason   string            `json:"reason"`
+	Time     time.Time         `json:"time"`
 }
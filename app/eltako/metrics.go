@@ -0,0 +1,114 @@
+package eltako
+
+import (
+	"strconv"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	httpRequests = metrics.Factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "eltako_http_requests_total",
+		Help: "Requests sent to the actors by method and status code",
+	}, []string{"method", "code"})
+	pollDuration = metrics.Factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "eltako_poll_duration_seconds",
+		Help:    "Duration of polling the position of an actor",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"actor"})
+	tokenRefreshes = metrics.Factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "eltako_token_refreshes_total",
+		Help: "Logins to the actors by result",
+	}, []string{"actor", "result"})
+	// CommandsReceived counts the commands by source, including rejected ones
+	CommandsReceived = metrics.Factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "eltako_commands_received_total",
+		Help: "Commands received by source",
+	}, []string{"source"})
+	// CommandsRejected counts the commands that were not executed by source and reason
+	CommandsRejected = metrics.Factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "eltako_commands_rejected_total",
+		Help: "Commands rejected by source and reason",
+	}, []string{"source", "reason"})
+)
+
+// Reasons for rejected commands
+const (
+	RejectedInvalid      = "invalid"
+	RejectedUnknownActor = "unknownActor"
+	RejectedUnknownGroup = "unknownGroup"
+	RejectedRefused      = "refused"
+)
+
+var (
+	actorPosition = prometheus.NewDesc("eltako_actor_position",
+		"Last known position of the actor in percent", []string{"actor"}, nil)
+	actorTilted = prometheus.NewDesc("eltako_actor_tilted",
+		"Whether the blinds of the actor are tilted", []string{"actor"}, nil)
+	actorTiltPosition = prometheus.NewDesc("eltako_actor_tilt_position",
+		"Position the blinds were last tilted at", []string{"actor"}, nil)
+	actorMoving = prometheus.NewDesc("eltako_actor_moving",
+		"Whether the actor is moving", []string{"actor"}, nil)
+	actorAvailable = prometheus.NewDesc("eltako_actor_available",
+		"Whether the actor is available", []string{"actor"}, nil)
+	actorHealth = prometheus.NewDesc("eltako_actor_health",
+		"Health of the actor, 1 for the current state", []string{"actor", "health"}, nil)
+)
+
+// actorCollector reads the cached state of the actors when the metrics are
+// scraped
+type actorCollector struct {
+	registry *ActorRegistry
+}
+
+func (c *actorCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- actorPosition
+	ch <- actorTilted
+	ch <- actorTiltPosition
+	ch <- actorMoving
+	ch <- actorAvailable
+	ch <- actorHealth
+}
+
+func (c *actorCollector) Collect(ch chan<- prometheus.Metric) {
+	flag := func(b bool) float64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	gauge := func(desc *prometheus.Desc, value float64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...)
+	}
+
+	healths := []Health{HealthPending, HealthOnline, HealthDegraded, HealthOffline, HealthReauthenticating}
+	for _, actor := range c.registry.AllActors() {
+		state := actor.CachedState()
+		gauge(actorPosition, float64(state.Position), actor.Name)
+		gauge(actorTilted, flag(state.Tilted), actor.Name)
+		gauge(actorTiltPosition, float64(state.TiltPosition), actor.Name)
+		gauge(actorMoving, flag(state.Moving), actor.Name)
+		gauge(actorAvailable, flag(state.Health.Available()), actor.Name)
+
+		current := actor.Health()
+		for _, health := range healths {
+			gauge(actorHealth, flag(current == health), actor.Name, string(health))
+		}
+	}
+}
+
+// RegisterActorMetrics exports the cached state of the actors of the
+// registry. It must only be called once.
+func RegisterActorMetrics(registry *ActorRegistry) {
+	metrics.Registry.MustRegister(&actorCollector{registry: registry})
+}
+
+// statusLabel returns the status code of the response or "error" when the
+// request failed without a response
+func statusLabel(code int, err error) string {
+	if err != nil {
+		return "error"
+	}
+	return strconv.Itoa(code)
+}
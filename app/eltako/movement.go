@@ -0,0 +1,78 @@
+package eltako
+
+import "github.com/mqtt-home/eltako-to-mqtt-gw/commands"
+
+// unknownTarget is used when a movement was detected from successive polls
+// instead of being started by a command
+const unknownTarget = -1
+
+// maxStableReads is the number of reads without a position change after
+// which a movement is considered finished even if the target was not reached
+const maxStableReads = 3
+
+func (s *ShadingActor) IsMoving() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.moving
+}
+
+// startMovement must be called with the lock held
+func (s *ShadingActor) startMovement(target int) {
+	s.moving = true
+	s.target = target
+	s.stableReads = 0
+	if s.lastRead != unknownTarget {
+		s.direction = directionOf(s.lastRead, target)
+	}
+}
+
+func directionOf(from int, to int) Direction {
+	switch {
+	case to > from:
+		return DirectionOpening
+	case to < from:
+		return DirectionClosing
+	default:
+		return DirectionStopped
+	}
+}
+
+// updateMovement tracks the movement state from a position read. It must be
+// called with the lock held.
+func (s *ShadingActor) updateMovement(position int) (started bool, stopped bool) {
+	delta := s.lastRead != unknownTarget && position != s.lastRead
+	lastRead := s.lastRead
+	s.lastRead = position
+
+	if delta {
+		s.stableReads = 0
+		if !s.moving {
+			// Moved by a wall switch or another controller
+			s.startMovement(unknownTarget)
+			s.lastCommandSource = commands.SourceExternal
+			started = true
+		}
+		if s.target == unknownTarget {
+			s.direction = directionOf(lastRead, position)
+		}
+	} else {
+		s.stableReads++
+	}
+
+	if s.moving && (position == s.target || s.stableReads >= maxStableReads ||
+		(s.target == unknownTarget && !delta)) {
+		s.moving = false
+		stopped = true
+	}
+	return started, stopped
+}
+
+// wakePolling lets the polling loop read the position immediately and switch
+// to the fast interval
+func (s *ShadingActor) wakePolling() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,194 @@
+package eltako
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/commands"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/storage"
+	"github.com/philipparndt/go-logger"
+)
+
+// StoredState is the part of the actor state that survives restarts
+type StoredState struct {
+	Position          int                 `json:"position"`
+	Tilted            bool                `json:"tilted"`
+	TiltPosition      int                 `json:"tiltPosition"`
+	LastCommand       *commands.LLCommand `json:"lastCommand,omitempty"`
+	LastCommandSource commands.Source     `json:"lastCommandSource,omitempty"`
+	// Health is the last known health. It is not restored, the health is
+	// determined again once the device has been contacted.
+	Health Health `json:"health"`
+	// UpdatedAt is the time the position was last read from the device
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// sameAs compares the states ignoring the time of the last read, so that a
+// poll that did not change anything does not cause a write
+func (s StoredState) sameAs(other StoredState) bool {
+	s.UpdatedAt = other.UpdatedAt
+	if s.LastCommand != nil && other.LastCommand != nil && *s.LastCommand == *other.LastCommand {
+		s.LastCommand = other.LastCommand
+	}
+	return s == other
+}
+
+// StateStore persists the runtime state of the actors in a JSON file, so the
+// tilt state and the last known position are available right after a restart.
+// Changes are kept in memory and written by a single writer, so disk I/O
+// never blocks the actors.
+type StateStore struct {
+	file    string
+	states  map[string]StoredState
+	seqs    map[string]uint64
+	dirty   bool
+	pending chan struct{}
+	mu      sync.Mutex
+	writeMu sync.Mutex
+}
+
+// Persisted is the store used by new actors. Persistence is disabled while nil.
+var Persisted *StateStore
+
+// persistSeq orders the states handed to the store
+var persistSeq atomic.Uint64
+
+// NewStateStore creates an empty store writing to the file
+func NewStateStore(file string) *StateStore {
+	return &StateStore{
+		file:    file,
+		states:  make(map[string]StoredState),
+		seqs:    make(map[string]uint64),
+		pending: make(chan struct{}, 1),
+	}
+}
+
+// OpenStateStore reads the states stored in the file. A missing file results
+// in an empty store.
+func OpenStateStore(file string) (*StateStore, error) {
+	store := NewStateStore(file)
+	if err := storage.ReadJSON(file, &store.states); err != nil {
+		return nil, fmt.Errorf("failed to read actor states from %s: %w", file, err)
+	}
+	return store, nil
+}
+
+func (p *StateStore) Get(name string) (StoredState, bool) {
+	if p == nil {
+		return StoredState{}, false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state, ok := p.states[strings.ToLower(name)]
+	return state, ok
+}
+
+// save keeps the state of the actor for the writer. States with a sequence
+// number older than the last saved one are ignored.
+func (p *StateStore) save(name string, state StoredState, seq uint64) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := strings.ToLower(name)
+	if seq <= p.seqs[key] {
+		return
+	}
+	p.seqs[key] = seq
+	if old, ok := p.states[key]; ok && old.sameAs(state) {
+		return
+	}
+	p.states[key] = state
+	p.dirty = true
+
+	select {
+	case p.pending <- struct{}{}:
+	default:
+	}
+}
+
+// Flush writes the states to disk if they changed since the last flush
+func (p *StateStore) Flush() {
+	if p == nil {
+		return
+	}
+
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+
+	p.mu.Lock()
+	if !p.dirty {
+		p.mu.Unlock()
+		return
+	}
+	states := make(map[string]StoredState, len(p.states))
+	for key, state := range p.states {
+		states[key] = state
+	}
+	p.dirty = false
+	p.mu.Unlock()
+
+	if err := storage.WriteJSON(p.file, states); err != nil {
+		logger.Error("Failed to save actor states", err)
+		p.mu.Lock()
+		p.dirty = true
+		p.mu.Unlock()
+	}
+}
+
+// Start writes the saved states in the background
+func (p *StateStore) Start() {
+	go func() {
+		for range p.pending {
+			p.Flush()
+		}
+	}()
+}
+
+// restore applies the stored state to a new actor
+func (s *ShadingActor) restore() {
+	state, ok := Persisted.Get(s.Name)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	s.Position = state.Position
+	s.Tilted = state.Tilted
+	s.TiltPosition = state.TiltPosition
+	s.lastCommand = state.LastCommand
+	s.lastCommandSource = state.LastCommandSource
+	s.updatedAt = state.UpdatedAt
+	s.mu.Unlock()
+
+	logger.Info(fmt.Sprintf("Restored state: position %d, tilted %t at %d, last health %s",
+		state.Position, state.Tilted, state.TiltPosition, state.Health), s)
+	s.storeState(false)
+}
+
+// storedState returns the state to persist and whether there is anything
+// worth keeping. It must be called with the lock held.
+func (s *ShadingActor) storedState() (StoredState, bool) {
+	if s.updatedAt.IsZero() {
+		// The position has never been read
+		return StoredState{}, false
+	}
+
+	return StoredState{
+		Position:          s.Position,
+		Tilted:            s.Tilted,
+		TiltPosition:      s.TiltPosition,
+		LastCommand:       s.lastCommand,
+		LastCommandSource: s.lastCommandSource,
+		Health:            s.health,
+		UpdatedAt:         s.updatedAt,
+	}, true
+}
@@ -3,64 +3,75 @@ package eltako
 import (
 	"fmt"
 	"strconv"
-	"sync"
 	"time"
 
 	"github.com/philipparndt/go-logger"
-	"github.com/philipparndt/mqtt-gateway/mqtt"
 )
 
-// PositionChangeEvent is sent to the global channel when an actor's position changes
-// You can extend this struct with more fields if needed
-// e.g. Tilt, Serial, etc.
-type PositionChangeEvent struct {
-	ActorName string
-	Position  int
-}
-
-var PositionChangeChan = make(chan PositionChangeEvent, 100)
-
-func (s *ShadingActor) schedulePolling(wg *sync.WaitGroup, pollingInterval int) {
-	errorCtr := 0
+// schedulePolling polls with the fast interval while the actor is moving and
+// falls back to the idle interval once the position is stable
+func (s *ShadingActor) schedulePolling(pollingInterval int, fastPollingInterval int) {
+	failures := 0
 	interval := time.Duration(pollingInterval) * time.Millisecond
-	logger.Info(fmt.Sprintf("Starting polling of %s with interval %s", s, interval))
-	wg.Done()
-	lastPosition := s.Position
+	fastInterval := time.Duration(fastPollingInterval) * time.Millisecond
+	logger.Info(fmt.Sprintf("Starting polling of %s with interval %s (%s while moving)", s, interval, fastInterval))
 	for {
+		if s.Health() == HealthOffline {
+			// The device may have been restarted, which invalidates the token
+			err := s.UpdateToken()
+			if err != nil {
+				failures++
+				delay := backoff(failures)
+				logger.Warn(fmt.Sprintf("Reconnecting %s failed, retrying in %s", s, delay), err)
+				time.Sleep(delay)
+				continue
+			}
+		}
+
+		start := time.Now()
 		position, err := s.getPosition()
+		pollDuration.WithLabelValues(s.Name).Observe(time.Since(start).Seconds())
 
 		if err != nil {
-			errorCtr++
-			if errorCtr >= 5 {
-				logger.Panic("Failed to poll position", err)
-			}
+			failures++
+			delay := backoff(failures)
+			logger.Warn(fmt.Sprintf("Failed to poll position of %s, retrying in %s", s, delay), err)
+			time.Sleep(delay)
 		} else {
 			logger.Debug("Polled position", s.Name, strconv.Itoa(position)+"%")
-			errorCtr = 0
-			if position != lastPosition {
-				lastPosition = position
-				s.Position = position
-				mqtt.PublishJSON(s.DisplayName(), PositionMessage{position})
-				// Send to global channel
-				PositionChangeChan <- PositionChangeEvent{ActorName: s.Name, Position: position}
+			failures = 0
+			if s.IsMoving() {
+				s.sleep(fastInterval)
+			} else {
+				s.sleep(interval)
 			}
-			time.Sleep(interval)
 		}
 	}
 }
 
-func (s *ShadingActor) scheduleUpdateToken(wg *sync.WaitGroup) {
+// sleep waits for the given duration or until polling is woken up by a command
+func (s *ShadingActor) sleep(duration time.Duration) {
+	select {
+	case <-time.After(duration):
+	case <-s.wake:
+	}
+}
+
+func (s *ShadingActor) scheduleUpdateToken() {
 	interval := time.Duration(60) * time.Minute
 	logger.Info(fmt.Sprintf("Scheduling token update of %s with interval %s", s.Name, interval))
-	wg.Done()
 	for {
+		time.Sleep(interval)
+
 		logger.Debug("Updating token")
 		err := s.UpdateToken()
 		if err != nil {
 			logger.Error("Failed updating token", err)
+			s.setHealth(HealthOffline)
+		} else {
+			s.recordSuccess()
 		}
 
 		logger.Debug("Token update done, sleeping for 60 minutes")
-		time.Sleep(interval)
 	}
 }
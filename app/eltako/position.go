@@ -2,6 +2,7 @@ package eltako
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/mqtt-home/eltako-to-mqtt-gw/retry"
@@ -22,14 +23,53 @@ func (s *ShadingActor) GetPosition() (int, error) {
 }
 
 func (s *ShadingActor) getPosition() (int, error) {
-	device, err := s.findDeviceByInfo("currentPosition")
+	position, err := s.readPosition()
 	if err != nil {
+		s.recordFailure(err)
 		return 0, err
 	}
+	s.recordSuccess()
 
 	s.mu.Lock()
 	oldPosition := s.Position
+	wasTilted := s.Tilted
+	s.Position = position
+
+	// Reads during a commanded movement, e.g. the tilt offset, are expected
+	// to change. setPosition already reset the tilt state for them.
+	commanded := s.moving && s.target != unknownTarget
+	changed := position != oldPosition
+	untilted := changed && wasTilted && !commanded
+	if untilted {
+		logger.Debug("Tilted disabled as position changed", s.Name, "from", oldPosition, "to", position)
+		s.Tilted = false
+	}
+
+	started, stopped := s.updateMovement(position)
 	s.mu.Unlock()
+	s.storeState(true)
+
+	if changed {
+		Events.Publish(Event{Type: EventPositionChanged, Actor: s, Position: position})
+		if untilted {
+			Events.Publish(Event{Type: EventTiltChanged, Actor: s, Position: position, Tilted: false})
+		}
+	}
+	if started {
+		Events.Publish(Event{Type: EventMovementStarted, Actor: s, Position: position})
+	}
+	if stopped {
+		Events.Publish(Event{Type: EventMovementStopped, Actor: s, Position: position})
+	}
+
+	return position, nil
+}
+
+func (s *ShadingActor) readPosition() (int, error) {
+	device, err := s.findDeviceByInfo("currentPosition")
+	if err != nil {
+		return 0, err
+	}
 
 	resp, err := s.client.Get("/devices/" + device.DeviceGuid + "/infos/currentPosition")
 	if err != nil {
@@ -40,7 +80,7 @@ func (s *ShadingActor) getPosition() (int, error) {
 	}(resp.Body)
 
 	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("failed to get position, status code: %d", resp.StatusCode)
+		return 0, &StatusError{Operation: "get position", StatusCode: resp.StatusCode}
 	}
 
 	var result map[string]interface{}
@@ -53,21 +93,7 @@ func (s *ShadingActor) getPosition() (int, error) {
 		return 0, fmt.Errorf("position not found in response")
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	s.Position = int(position)
-
-	if s.Position != oldPosition {
-		PositionChangeChan <- PositionChangeEvent{
-			ActorName: s.Name,
-			Position:  s.Position,
-		}
-		logger.Debug("Tilted disabled as position changed", s.Name, "from", oldPosition, "to", s.Position)
-		s.Tilted = false
-	}
-
-	return s.Position, nil
+	return int(position), nil
 }
 
 func (s *ShadingActor) SetPosition(position int) (bool, error) {
@@ -76,7 +102,13 @@ func (s *ShadingActor) SetPosition(position int) (bool, error) {
 	}
 
 	return retry.Times[bool](3, func() (bool, error) {
-		return s.setPosition(position)
+		ok, err := s.setPosition(position)
+		if err != nil {
+			s.recordFailure(err)
+		} else {
+			s.recordSuccess()
+		}
+		return ok, err
 	})
 }
 
@@ -95,11 +127,16 @@ func (s *ShadingActor) setPosition(position int) (bool, error) {
 		return false, err
 	}
 
-	logger.Debug("Tilted disabled as position changed (setPosition)", s.Name, "from", position, "to", s.Position)
+	logger.Debug("Tilted disabled as position changed (setPosition)", s.Name, "to", position)
 	s.mu.Lock()
+	wasTilted := s.Tilted
 	s.Tilted = false
 	s.mu.Unlock()
 
+	if wasTilted {
+		Events.Publish(Event{Type: EventTiltChanged, Actor: s, Position: position, Tilted: false})
+	}
+
 	resp, err := s.client.Put("/devices/"+device.DeviceGuid+"/functions/targetPosition", bytes.NewReader(body))
 	if err != nil {
 		return false, err
@@ -109,16 +146,22 @@ func (s *ShadingActor) setPosition(position int) (bool, error) {
 	}(resp.Body)
 
 	if resp.StatusCode != http.StatusAccepted {
-		return false, fmt.Errorf("failed to set position, status code: %d", resp.StatusCode)
+		return false, &StatusError{Operation: "set position", StatusCode: resp.StatusCode}
 	}
 
-	s.Position = position
-	logger.Debug("Persisting position", s.Name, "pos", position)
+	// Position keeps the last read, the target is tracked by the movement
+	s.mu.Lock()
+	s.startMovement(position)
+	current := s.Position
+	s.mu.Unlock()
+	s.storeState(false)
+	Events.Publish(Event{Type: EventMovementStarted, Actor: s, Position: current})
+	s.wakePolling()
 
 	return true, nil
 }
 
-func (s *ShadingActor) WaitForPosition(waitGroup *sync.WaitGroup, position int, timeout int) error {
+func (s *ShadingActor) WaitForPosition(ctx context.Context, waitGroup *sync.WaitGroup, position int, timeout int) error {
 	if position < 0 || position > 100 {
 		return fmt.Errorf("invalid position")
 	}
@@ -146,14 +189,19 @@ func (s *ShadingActor) WaitForPosition(waitGroup *sync.WaitGroup, position int,
 				return
 			}
 
-			time.Sleep(500 * time.Millisecond)
+			select {
+			case <-ctx.Done():
+				logger.Debug(fmt.Sprintf("Stopped waiting for position %d", position))
+				return
+			case <-time.After(500 * time.Millisecond):
+			}
 		}
 	}()
 
 	return nil
 }
 
-func (s *ShadingActor) SetAndWaitForPosition(waitGroup *sync.WaitGroup, position int, timeout int) error {
+func (s *ShadingActor) SetAndWaitForPosition(ctx context.Context, waitGroup *sync.WaitGroup, position int, timeout int) error {
 	if position < 0 || position > 100 {
 		return fmt.Errorf("invalid position")
 	}
@@ -163,5 +211,5 @@ func (s *ShadingActor) SetAndWaitForPosition(waitGroup *sync.WaitGroup, position
 		return err
 	}
 
-	return s.WaitForPosition(waitGroup, position, timeout)
+	return s.WaitForPosition(ctx, waitGroup, position, timeout)
 }
@@ -0,0 +1,68 @@
+package eltako
+
+import (
+	"encoding/json"
+
+	"github.com/philipparndt/mqtt-gateway/mqtt"
+)
+
+// StartMQTTPublisher publishes the state changes of all actors and the
+// aggregated state of their groups to MQTT
+func StartMQTTPublisher(bus *EventBus, registry *ActorRegistry) {
+	sub := bus.Subscribe("mqtt", 1000)
+
+	go func() {
+		for event := range sub.C {
+			actor := event.Actor
+			switch event.Type {
+			case EventPositionChanged, EventMovementStarted, EventMovementStopped:
+				mqtt.PublishJSON(actor.DisplayName(), NewPositionMessage(actor.CachedState()))
+				for _, group := range registry.GroupsOf(actor) {
+					mqtt.PublishJSON("group/"+group.Name, group.State())
+				}
+			case EventHealthChanged:
+				mqtt.PublishAbsolute(actor.HealthTopic(), string(event.Health), true)
+			case EventActorOnline:
+				mqtt.PublishAbsolute(actor.AvailabilityTopic(), AvailabilityOnline, true)
+			case EventActorOffline:
+				mqtt.PublishAbsolute(actor.AvailabilityTopic(), AvailabilityOffline, true)
+			case EventLockChanged:
+				mqtt.PublishJSON(actor.LockTopic(), actor.LockState())
+			case EventInterlockChanged:
+				mqtt.PublishJSON(actor.InterlockTopic(), actor.Interlock())
+			case EventCommandFailed:
+				if IsRefused(event.Error) {
+					publishRefused(event)
+				}
+			case EventCommandChanged:
+				if event.Record.Status.IsFinished() {
+					publishResult(event)
+				}
+			}
+		}
+	}()
+}
+
+// publishRefused publishes refused commands without retaining them
+func publishRefused(event Event) {
+	message, err := json.Marshal(RefusedMessage{
+		Action:   event.Command.Action,
+		Position: event.Command.Position,
+		Source:   event.Command.Source,
+		Reason:   event.Error.Error(),
+		Time:     event.Time,
+	})
+	if err != nil {
+		return
+	}
+	mqtt.PublishAbsolute(event.Actor.RefusedTopic(), string(message), false)
+}
+
+// publishResult publishes the record of a finished command without retaining it
+func publishResult(event Event) {
+	message, err := json.Marshal(event.Record)
+	if err != nil {
+		return
+	}
+	mqtt.PublishAbsolute(event.Actor.ResultTopic(), string(message), false)
+}
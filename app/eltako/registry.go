@@ -1,26 +1,74 @@
 package eltako
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/config"
+)
+
+// ActorListener is notified when actors are added to the registry. Actors are
+// never removed, devices that disappear are marked offline instead.
+type ActorListener interface {
+	ActorAdded(actor *ShadingActor)
+}
 
 type ActorRegistry struct {
-	Actors map[string]*ShadingActor
+	Actors    map[string]*ShadingActor
+	Groups    map[string]*ActorGroup
+	listeners []ActorListener
+	mu        sync.Mutex
 }
 
 func NewActorRegistry() *ActorRegistry {
 	return &ActorRegistry{
 		Actors: make(map[string]*ShadingActor),
+		Groups: make(map[string]*ActorGroup),
 	}
 }
 
+func (r *ActorRegistry) AddListener(listener ActorListener) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.listeners = append(r.listeners, listener)
+}
+
 func (r *ActorRegistry) AddActor(actor *ShadingActor) {
+	r.mu.Lock()
 	r.Actors[strings.ToLower(actor.Name)] = actor
+	listeners := append([]ActorListener(nil), r.listeners...)
+	r.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener.ActorAdded(actor)
+	}
 }
 
 func (r *ActorRegistry) GetActor(name string) *ShadingActor {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	return r.Actors[strings.ToLower(name)]
 }
 
+// AllActors returns a snapshot of the registered actors
+func (r *ActorRegistry) AllActors() []*ShadingActor {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	actors := make([]*ShadingActor, 0, len(r.Actors))
+	for _, actor := range r.Actors {
+		actors = append(actors, actor)
+	}
+	return actors
+}
+
 func (r *ActorRegistry) GetActorBySN(sn string) *ShadingActor {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	for _, actor := range r.Actors {
 		if actor.Serial == sn {
 			return actor
@@ -29,3 +77,78 @@ func (r *ActorRegistry) GetActorBySN(sn string) *ShadingActor {
 
 	return nil
 }
+
+func (r *ActorRegistry) AddGroup(group *ActorGroup) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Groups[strings.ToLower(group.Name)] = group
+}
+
+func (r *ActorRegistry) GetGroup(name string) *ActorGroup {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.Groups[strings.ToLower(name)]
+}
+
+// AllGroups returns a snapshot of the registered groups
+func (r *ActorRegistry) AllGroups() []*ActorGroup {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	groups := make([]*ActorGroup, 0, len(r.Groups))
+	for _, group := range r.Groups {
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// GroupsOf returns all groups the actor is a member of
+func (r *ActorRegistry) GroupsOf(actor *ShadingActor) []*ActorGroup {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var groups []*ActorGroup
+	for _, group := range r.Groups {
+		if group.Contains(actor) {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// Initialized reports the health of each configured device. It fails while
+// a device has no actor yet (e.g. it was not discovered) or the actor has not
+// answered since the start.
+func (r *ActorRegistry) Initialized(devices []config.Device) (map[string]Health, error) {
+	healths := make(map[string]Health, len(devices))
+	var missing []string
+	for _, device := range devices {
+		if device.Ip == "" && device.Serial == "" {
+			// Skipped at startup, the actor is never created
+			continue
+		}
+
+		actor := r.GetActor(device.Name)
+		if actor == nil {
+			healths[device.Name] = HealthPending
+			missing = append(missing, device.Name)
+			continue
+		}
+
+		health := actor.Health()
+		if health == "" {
+			health = HealthPending
+		}
+		healths[device.Name] = health
+		if health == HealthPending {
+			missing = append(missing, device.Name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return healths, fmt.Errorf("actors not initialized: %s", strings.Join(missing, ", "))
+	}
+	return healths, nil
+}
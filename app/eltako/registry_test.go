@@ -0,0 +1,30 @@
+package eltako
+
+import (
+	"testing"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/config"
+)
+
+func TestInitializedSkipsDevicesWithoutAddress(t *testing.T) {
+	registry := NewActorRegistry()
+	actor, _ := newTestActor(t, 100)
+	registry.AddActor(actor)
+
+	devices := []config.Device{
+		{Name: actor.Name, Ip: actor.Address()},
+		{Name: "unconfigured"},
+	}
+	healths, err := registry.Initialized(devices)
+	if err != nil {
+		t.Fatalf("expected the device without address to be ignored, got %v", err)
+	}
+	if _, ok := healths["unconfigured"]; ok || healths[actor.Name] != HealthOnline {
+		t.Errorf("unexpected healths %v", healths)
+	}
+
+	devices = append(devices, config.Device{Name: "discovered", Serial: "1234"})
+	if _, err := registry.Initialized(devices); err == nil {
+		t.Error("expected a device waiting for discovery to be reported")
+	}
+}
@@ -0,0 +1,134 @@
+package eltako
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/commands"
+	"github.com/philipparndt/go-logger"
+)
+
+type Outcome string
+
+const (
+	// OutcomeAccepted is used for commands that are not awaited, e.g. stop
+	OutcomeAccepted    Outcome = "accepted"
+	OutcomeReached     Outcome = "reached"
+	OutcomeStopped     Outcome = "stopped"
+	OutcomeTimeout     Outcome = "timeout"
+	OutcomeRefused     Outcome = "refused"
+	OutcomeCancelled   Outcome = "cancelled"
+	OutcomeSuperseded  Outcome = "superseded"
+	OutcomeDeviceError Outcome = "deviceError"
+	OutcomeFailed      Outcome = "failed"
+)
+
+// Result is the outcome of a command that was executed synchronously
+type Result struct {
+	Outcome  Outcome `json:"outcome"`
+	Position int     `json:"position"`
+	// StatusCode is the HTTP status code of the device for device errors
+	StatusCode int    `json:"statusCode,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ResultOf converts the error of a command to a result
+func (s *ShadingActor) ResultOf(err error) Result {
+	result := Result{
+		Outcome:  OutcomeFailed,
+		Position: s.CachedState().Position,
+		Error:    err.Error(),
+	}
+
+	var statusErr *StatusError
+	var stoppedErr *StoppedError
+	switch {
+	case IsRefused(err):
+		result.Outcome = OutcomeRefused
+	case errors.Is(err, ErrCancelled):
+		result.Outcome = OutcomeCancelled
+	case errors.Is(err, ErrSuperseded):
+		result.Outcome = OutcomeSuperseded
+	case errors.As(err, &stoppedErr):
+		result.Outcome = OutcomeStopped
+	case errors.As(err, &statusErr):
+		result.Outcome = OutcomeDeviceError
+		result.StatusCode = statusErr.StatusCode
+	case errors.Is(err, context.DeadlineExceeded):
+		result.Outcome = OutcomeTimeout
+	}
+	return result
+}
+
+// Execute applies the command and waits until the shading reached the
+// target position or the context is done. The command keeps running when
+// the context is done before.
+func (s *ShadingActor) Execute(ctx context.Context, command commands.LLCommand) Result {
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Apply(command)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return s.ResultOf(err)
+		}
+	case <-ctx.Done():
+		return s.ResultOf(ctx.Err())
+	}
+
+	switch command.Action {
+	case commands.LLActionSet:
+		if err := s.awaitPosition(ctx, command.Position); err != nil {
+			return s.ResultOf(err)
+		}
+	case commands.LLActionStop:
+		return Result{Outcome: OutcomeAccepted, Position: s.CachedState().Position}
+	}
+	// The tilt sequence already waited for the position
+	return Result{Outcome: OutcomeReached, Position: s.CachedState().Position}
+}
+
+// StoppedError is returned when the movement ended before the target
+// position was reached, e.g. after a stop command or a wall switch press
+type StoppedError struct {
+	Position int
+	Target   int
+}
+
+func (e *StoppedError) Error() string {
+	return fmt.Sprintf("movement stopped at %d before reaching %d", e.Position, e.Target)
+}
+
+// awaitPosition polls the position until it equals the target. It uses the
+// fast polling interval, as the movement is considered finished after a few
+// reads without a position change.
+func (s *ShadingActor) awaitPosition(ctx context.Context, target int) error {
+	interval := time.Duration(s.device.FastPollingInterval) * time.Millisecond
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	for {
+		position, err := s.getPosition()
+		if err != nil {
+			return err
+		}
+		if position == target {
+			logger.Debug(fmt.Sprintf("Position %d reached", target), s)
+			return nil
+		}
+		if !s.IsMoving() {
+			return &StoppedError{Position: position, Target: target}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package eltako
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/commands"
+)
+
+// ActorState is a snapshot of the last known state of an actor
+type ActorState struct {
+	Name         string
+	Position     int
+	Tilted       bool
+	TiltPosition int
+	Health       Health
+	Moving       bool
+	Direction    Direction
+	// TargetPosition is nil when not moving or the target of the movement is unknown
+	TargetPosition    *int
+	LastCommandSource commands.Source
+	// UpdatedAt is the time the position was last read from the device
+	UpdatedAt time.Time
+}
+
+func (a ActorState) IsStale(maxAge time.Duration) bool {
+	return a.UpdatedAt.IsZero() || time.Since(a.UpdatedAt) > maxAge
+}
+
+// StateCache holds the last known state of all actors. It is fed by polling
+// and command results so readers do not need to ask the devices.
+type StateCache struct {
+	states map[string]ActorState
+	mu     sync.RWMutex
+}
+
+var States = NewStateCache()
+
+func NewStateCache() *StateCache {
+	return &StateCache{
+		states: make(map[string]ActorState),
+	}
+}
+
+func (c *StateCache) Get(name string) (ActorState, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	state, ok := c.states[strings.ToLower(name)]
+	return state, ok
+}
+
+func (c *StateCache) put(state ActorState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.states[strings.ToLower(state.Name)] = state
+}
+
+// storeState copies the current state of the actor to the cache. Use
+// read=true when the position has just been read from the device.
+func (s *ShadingActor) storeState(read bool) {
+	s.mu.Lock()
+	if read {
+		s.updatedAt = time.Now()
+	}
+	state := ActorState{
+		Name:              s.Name,
+		Position:          s.Position,
+		Tilted:            s.Tilted,
+		TiltPosition:      s.TiltPosition,
+		Health:            s.health,
+		Moving:            s.moving,
+		Direction:         DirectionStopped,
+		LastCommandSource: s.lastCommandSource,
+		UpdatedAt:         s.updatedAt,
+	}
+	if s.moving {
+		state.Direction = s.direction
+	}
+	if s.moving && s.target != unknownTarget {
+		target := s.target
+		state.TargetPosition = &target
+	}
+	stored, persist := s.storedState()
+	// The sequence number keeps the saves in the order of the changes
+	seq := persistSeq.Add(1)
+	s.mu.Unlock()
+
+	States.put(state)
+	if persist {
+		Persisted.save(s.Name, stored, seq)
+	}
+}
+
+// CachedState returns the last known state without asking the device
+func (s *ShadingActor) CachedState() ActorState {
+	state, ok := States.Get(s.Name)
+	if !ok {
+		s.storeState(false)
+		state, _ = States.Get(s.Name)
+	}
+	return state
+}
+
+// Refresh reads the position in the background. Concurrent calls while a
+// refresh is running are ignored.
+func (s *ShadingActor) Refresh() {
+	if !s.refreshing.CompareAndSwap(false, true) {
+		return
+	}
+
+	go func() {
+		defer s.refreshing.Store(false)
+		_, _ = s.getPosition()
+	}()
+}
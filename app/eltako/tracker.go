@@ -0,0 +1,182 @@
+package eltako
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/commands"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/config"
+)
+
+type CommandStatus string
+
+const (
+	CommandQueued     CommandStatus = "queued"
+	CommandRunning    CommandStatus = "running"
+	CommandSucceeded  CommandStatus = "succeeded"
+	CommandFailed     CommandStatus = "failed"
+	CommandCancelled  CommandStatus = "cancelled"
+	CommandSuperseded CommandStatus = "superseded"
+)
+
+var (
+	// ErrCancelled is the cause of motions cancelled by a stop command
+	ErrCancelled = fmt.Errorf("cancelled by a stop command: %w", context.Canceled)
+	// ErrSuperseded is the cause of motions cancelled by a newer command
+	ErrSuperseded = fmt.Errorf("superseded by a newer command: %w", context.Canceled)
+)
+
+func (c CommandStatus) IsFinished() bool {
+	return c != CommandQueued && c != CommandRunning
+}
+
+// CommandRecord is the lifecycle of a command applied to an actor
+type CommandRecord struct {
+	ID         string            `json:"id"`
+	RequestID  string            `json:"requestId,omitempty"`
+	Actor      string            `json:"actor"`
+	Action     commands.LLAction `json:"action"`
+	Position   int               `json:"position"`
+	Source     commands.Source   `json:"source"`
+	Status     CommandStatus     `json:"status"`
+	Error      string            `json:"error,omitempty"`
+	CreatedAt  time.Time         `json:"createdAt"`
+	StartedAt  *time.Time        `json:"startedAt,omitempty"`
+	FinishedAt *time.Time        `json:"finishedAt,omitempty"`
+}
+
+// CommandTracker keeps the records of the most recent commands
+type CommandTracker struct {
+	records map[string]*CommandRecord
+	order   []string
+	limit   int
+	mu      sync.Mutex
+}
+
+// Commands tracks the commands of all actors
+var Commands = NewCommandTracker(1000)
+
+func NewCommandTracker(limit int) *CommandTracker {
+	return &CommandTracker{
+		records: make(map[string]*CommandRecord),
+		limit:   limit,
+	}
+}
+
+func statusOf(err error) CommandStatus {
+	switch {
+	case err == nil:
+		return CommandSucceeded
+	case errors.Is(err, ErrSuperseded):
+		return CommandSuperseded
+	case errors.Is(err, ErrCancelled):
+		return CommandCancelled
+	default:
+		return CommandFailed
+	}
+}
+
+// track registers the command as queued unless it is already known. A
+// missing ID is assigned to the command.
+func (t *CommandTracker) track(actor *ShadingActor, command *commands.LLCommand) {
+	if command.ID == "" {
+		command.ID = commands.NewID()
+	}
+
+	t.mu.Lock()
+	if _, ok := t.records[command.ID]; ok {
+		t.mu.Unlock()
+		return
+	}
+
+	record := &CommandRecord{
+		ID:        command.ID,
+		RequestID: command.RequestID,
+		Actor:     actor.Name,
+		Action:    command.Action,
+		Position:  command.Position,
+		Source:    command.Source,
+		Status:    CommandQueued,
+		CreatedAt: time.Now(),
+	}
+	t.records[record.ID] = record
+	t.order = append(t.order, record.ID)
+	if len(t.order) > t.limit {
+		delete(t.records, t.order[0])
+		t.order = t.order[1:]
+	}
+	snapshot := *record
+	t.mu.Unlock()
+
+	Events.Publish(Event{Type: EventCommandChanged, Actor: actor, Record: &snapshot})
+}
+
+func (t *CommandTracker) start(actor *ShadingActor, id string) {
+	t.update(actor, id, func(record *CommandRecord) {
+		now := time.Now()
+		record.Status = CommandRunning
+		record.StartedAt = &now
+	})
+}
+
+func (t *CommandTracker) finish(actor *ShadingActor, id string, err error) {
+	t.update(actor, id, func(record *CommandRecord) {
+		now := time.Now()
+		record.Status = statusOf(err)
+		record.FinishedAt = &now
+		if err != nil {
+			record.Error = err.Error()
+		}
+	})
+}
+
+func (t *CommandTracker) update(actor *ShadingActor, id string, update func(record *CommandRecord)) {
+	t.mu.Lock()
+	record, ok := t.records[id]
+	if !ok {
+		t.mu.Unlock()
+		return
+	}
+	update(record)
+	snapshot := *record
+	t.mu.Unlock()
+
+	Events.Publish(Event{Type: EventCommandChanged, Actor: actor, Record: &snapshot})
+}
+
+func (t *CommandTracker) Get(id string) (CommandRecord, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	record, ok := t.records[id]
+	if !ok {
+		return CommandRecord{}, false
+	}
+	return *record, true
+}
+
+// ForActor returns the records of the actor, newest first
+func (t *CommandTracker) ForActor(name string) []CommandRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := []CommandRecord{}
+	for _, record := range t.records {
+		if strings.EqualFold(record.Actor, name) {
+			result = append(result, *record)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return result
+}
+
+func (s *ShadingActor) ResultTopic() string {
+	return config.Get().MQTT.Topic + "/" + s.DisplayName() + "/result"
+}
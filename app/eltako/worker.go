@@ -0,0 +1,98 @@
+package eltako
+
+import (
+	"context"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/commands"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/config"
+)
+
+// job is a command waiting for the worker of its actor. The outcome is
+// delivered exactly once on done.
+type job struct {
+	command commands.LLCommand
+	done    chan error
+}
+
+// preempts tells whether the command cancels the command in flight and the
+// pending ones instead of waiting behind them. Stop and protection commands
+// always do, other commands depend on the configured policy.
+func (s *ShadingActor) preempts(command commands.LLCommand) bool {
+	if command.Action == commands.LLActionStop || command.Source == commands.SourceProtection {
+		return true
+	}
+	return s.device.CommandPolicy != config.CommandPolicyQueue
+}
+
+// enqueue hands the command to the worker of the actor, which executes the
+// commands one at a time so that exactly one motion owns the device.
+func (s *ShadingActor) enqueue(command commands.LLCommand) <-chan error {
+	j := &job{command: command, done: make(chan error, 1)}
+
+	cause := ErrSuperseded
+	if command.Action == commands.LLActionStop {
+		cause = ErrCancelled
+	}
+
+	var dropped []*job
+	s.mu.Lock()
+	if s.preempts(command) {
+		dropped = s.queue
+		s.queue = nil
+		if s.cancelMotion != nil {
+			s.cancelMotion(cause)
+			s.cancelMotion = nil
+		}
+	}
+	s.queue = append(s.queue, j)
+	s.mu.Unlock()
+
+	for _, d := range dropped {
+		Commands.finish(s, d.command.ID, cause)
+		d.done <- cause
+	}
+
+	s.startWorker.Do(func() { go s.work() })
+	select {
+	case s.pending <- struct{}{}:
+	default:
+	}
+	return j.done
+}
+
+// next takes the oldest pending job and creates the motion context it runs
+// with. Both happen under the lock so that a preempting command either
+// removes the job from the queue or cancels its context.
+func (s *ShadingActor) next() (*job, context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queue) == 0 {
+		return nil, nil
+	}
+	j := s.queue[0]
+	s.queue = s.queue[1:]
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	s.cancelMotion = cancel
+	return j, ctx
+}
+
+func (s *ShadingActor) work() {
+	for range s.pending {
+		for {
+			j, ctx := s.next()
+			if j == nil {
+				break
+			}
+			j.done <- s.execute(ctx, j.command)
+		}
+	}
+}
+
+// QueueLength returns the number of commands waiting behind the one in flight
+func (s *ShadingActor) QueueLength() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queue)
+}
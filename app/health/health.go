@@ -0,0 +1,88 @@
+// Package health aggregates the readiness of the subsystems of the gateway.
+package health
+
+import (
+	"sync"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+// Check returns details about a subsystem and an error while it is not ready
+type Check func() (any, error)
+
+type SubsystemReport struct {
+	Status  Status `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Details any    `json:"details,omitempty"`
+}
+
+// Report is ready when all subsystems are up
+type Report struct {
+	Status     Status                     `json:"status"`
+	Subsystems map[string]SubsystemReport `json:"subsystems"`
+}
+
+type Checker struct {
+	checks map[string]Check
+	mu     sync.Mutex
+}
+
+// Default is the checker the subsystems register with
+var Default = NewChecker()
+
+// StartedAt is the time the process was started
+var StartedAt = time.Now()
+
+func NewChecker() *Checker {
+	return &Checker{
+		checks: make(map[string]Check),
+	}
+}
+
+// Register adds a check, replacing an existing one with the same name
+func (c *Checker) Register(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks[name] = check
+}
+
+// Ready runs all checks
+func (c *Checker) Ready() Report {
+	c.mu.Lock()
+	checks := make(map[string]Check, len(c.checks))
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	c.mu.Unlock()
+
+	report := Report{
+		Status:     StatusUp,
+		Subsystems: make(map[string]SubsystemReport, len(checks)),
+	}
+	for name, check := range checks {
+		details, err := check()
+		subsystem := SubsystemReport{Status: StatusUp, Details: details}
+		if err != nil {
+			subsystem.Status = StatusDown
+			subsystem.Error = err.Error()
+			report.Status = StatusDown
+		}
+		report.Subsystems[name] = subsystem
+	}
+	return report
+}
+
+func Register(name string, check Check) {
+	Default.Register(name, check)
+}
+
+func Ready() Report {
+	return Default.Ready()
+}
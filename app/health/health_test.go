@@ -0,0 +1,33 @@
+package health
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReady(t *testing.T) {
+	checker := NewChecker()
+	checker.Register("mqtt", func() (any, error) {
+		return nil, nil
+	})
+
+	if report := checker.Ready(); report.Status != StatusUp || report.Subsystems["mqtt"].Status != StatusUp {
+		t.Errorf("expected all subsystems up, got %+v", report)
+	}
+
+	checker.Register("actors", func() (any, error) {
+		return map[string]string{"living": "pending"}, errors.New("actors not initialized: living")
+	})
+
+	report := checker.Ready()
+	if report.Status != StatusDown {
+		t.Errorf("expected report to be down, got %s", report.Status)
+	}
+	actors := report.Subsystems["actors"]
+	if actors.Status != StatusDown || actors.Error != "actors not initialized: living" || actors.Details == nil {
+		t.Errorf("expected actors to be down with details, got %+v", actors)
+	}
+	if report.Subsystems["mqtt"].Status != StatusUp {
+		t.Errorf("expected mqtt to stay up, got %+v", report.Subsystems["mqtt"])
+	}
+}
@@ -0,0 +1,59 @@
+package health
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/philipparndt/mqtt-gateway/mqtt"
+)
+
+// heartbeatInterval is the interval in which a message is sent to the broker
+// and expected to be received back
+const heartbeatInterval = 30 * time.Second
+
+type heartbeat struct {
+	lastSeen time.Time
+	mu       sync.Mutex
+}
+
+type HeartbeatDetails struct {
+	LastSeen *time.Time `json:"lastSeen,omitempty"`
+}
+
+// StartMQTTHeartbeat publishes a non-retained message to the topic and
+// subscribes to it. The MQTT subsystem is ready while the messages make the
+// round trip through the broker.
+func StartMQTTHeartbeat(topic string) {
+	h := &heartbeat{}
+	mqtt.Subscribe(topic, func(topic string, payload []byte) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.lastSeen = time.Now()
+	})
+
+	go func() {
+		for {
+			mqtt.PublishAbsolute(topic, time.Now().Format(time.RFC3339), false)
+			time.Sleep(heartbeatInterval)
+		}
+	}()
+
+	Register("mqtt", h.check)
+}
+
+func (h *heartbeat) check() (any, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.lastSeen.IsZero() {
+		return HeartbeatDetails{}, errors.New("no heartbeat received yet")
+	}
+	lastSeen := h.lastSeen
+	details := HeartbeatDetails{LastSeen: &lastSeen}
+	if age := time.Since(lastSeen); age > 2*heartbeatInterval {
+		return details, fmt.Errorf("no heartbeat received for %s", age.Round(time.Second))
+	}
+	return details, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/config"
+)
+
+// healthcheck asks the running gateway for its readiness (or liveness with
+// -live) and returns the exit code for a Docker HEALTHCHECK. It needs no curl
+// in the image.
+//
+//	eltako-to-mqtt-gw healthcheck [-live] [-timeout 5s] <config file>
+func healthcheck(args []string) int {
+	flags := flag.NewFlagSet("healthcheck", flag.ContinueOnError)
+	live := flags.Bool("live", false, "only check that the process is up (/healthz)")
+	timeout := flags.Duration("timeout", 5*time.Second, "timeout of the request")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: healthcheck [-live] [-timeout 5s] <config file>")
+		return 2
+	}
+
+	cfg, err := config.LoadConfig(flags.Arg(0))
+	if err != nil {
+		return 1
+	}
+	if !cfg.Web.Enabled {
+		// Nothing to ask, a gateway without web server must not be reported
+		// as unhealthy
+		fmt.Fprintln(os.Stderr, "web server disabled, skipping healthcheck")
+		return 0
+	}
+
+	path := "/readyz"
+	if *live {
+		path = "/healthz"
+	}
+
+	client := http.Client{Timeout: *timeout}
+	resp, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d%s", cfg.Web.Port, path))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	fmt.Println(string(body))
+	if resp.StatusCode != http.StatusOK {
+		return 1
+	}
+	return 0
+}
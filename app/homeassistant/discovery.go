@@ -0,0 +1,166 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/config"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/eltako"
+	"github.com/philipparndt/go-logger"
+	"github.com/philipparndt/mqtt-gateway/mqtt"
+)
+
+const uniqueIdPrefix = "eltako_"
+
+// stateTemplate maps the published state to the states of a Home Assistant cover
+const stateTemplate = "{% if value_json.moving %}{{ value_json.direction }}" +
+	"{% elif value_json.position == 0 %}closed{% else %}open{% endif %}"
+
+var invalidObjectIdChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+type Device struct {
+	Identifiers  []string `json:"identifiers"`
+	Manufacturer string   `json:"manufacturer"`
+	Model        string   `json:"model"`
+	Name         string   `json:"name"`
+	SerialNumber string   `json:"serial_number,omitempty"`
+}
+
+type Availability struct {
+	Topic string `json:"topic"`
+}
+
+type CoverConfig struct {
+	Name                string         `json:"name"`
+	Availability        []Availability `json:"availability"`
+	AvailabilityMode    string         `json:"availability_mode"`
+	UniqueId            string         `json:"unique_id"`
+	DeviceClass         string         `json:"device_class"`
+	CommandTopic        string         `json:"command_topic"`
+	PayloadOpen         string         `json:"payload_open"`
+	PayloadClose        string         `json:"payload_close"`
+	PayloadStop         string         `json:"payload_stop"`
+	StateTopic          string         `json:"state_topic"`
+	ValueTemplate       string         `json:"value_template"`
+	PositionTopic       string         `json:"position_topic"`
+	PositionTemplate    string         `json:"position_template"`
+	SetPositionTopic    string         `json:"set_position_topic"`
+	SetPositionTemplate string         `json:"set_position_template"`
+	TiltCommandTopic    string         `json:"tilt_command_topic"`
+	TiltCommandTemplate string         `json:"tilt_command_template"`
+	TiltMin             int            `json:"tilt_min"`
+	TiltMax             int            `json:"tilt_max"`
+	Device              Device         `json:"device"`
+}
+
+// DiscoveryPublisher publishes retained Home Assistant MQTT discovery configs
+// for every actor added to the registry and clears them when the actor is removed.
+type DiscoveryPublisher struct {
+	prefix  string
+	topic   string
+	devices []config.Device
+}
+
+func NewDiscoveryPublisher(cfg config.Config) *DiscoveryPublisher {
+	return &DiscoveryPublisher{
+		prefix:  cfg.HomeAssistant.DiscoveryPrefix,
+		topic:   cfg.MQTT.Topic,
+		devices: cfg.Eltako.Devices,
+	}
+}
+
+// objectId prefers the serial number as it stays stable when the actor is renamed
+func objectId(serial string, name string) string {
+	id := serial
+	if id == "" {
+		id = name
+	}
+	return invalidObjectIdChars.ReplaceAllString(strings.ToLower(id), "_")
+}
+
+func (p *DiscoveryPublisher) configTopic(objectId string) string {
+	return fmt.Sprintf("%s/cover/%s/config", p.prefix, objectId)
+}
+
+func (p *DiscoveryPublisher) coverConfig(actor *eltako.ShadingActor) CoverConfig {
+	id := objectId(actor.Serial, actor.Name)
+	name := actor.DisplayName()
+	base := p.topic + "/" + name
+
+	identifier := uniqueIdPrefix + id
+	return CoverConfig{
+		Name: name,
+		Availability: []Availability{
+			{Topic: p.topic + "/bridge/state"},
+			{Topic: actor.AvailabilityTopic()},
+		},
+		AvailabilityMode:    "all",
+		UniqueId:            identifier,
+		DeviceClass:         "blind",
+		CommandTopic:        base + "/set",
+		PayloadOpen:         `{"action":"open"}`,
+		PayloadClose:        `{"action":"close"}`,
+		PayloadStop:         `{"action":"stop"}`,
+		StateTopic:          base,
+		ValueTemplate:       stateTemplate,
+		PositionTopic:       base,
+		PositionTemplate:    "{{ value_json.position }}",
+		SetPositionTopic:    base + "/set",
+		SetPositionTemplate: `{"action":"set","position":{{ position }}}`,
+		TiltCommandTopic:    base + "/set",
+		TiltCommandTemplate: `{"action":"tilt","position":{{ tilt_position }}}`,
+		TiltMin:             0,
+		TiltMax:             100,
+		Device: Device{
+			Identifiers:  []string{identifier},
+			Manufacturer: "Eltako",
+			Model:        actor.Model,
+			Name:         name,
+			SerialNumber: actor.Serial,
+		},
+	}
+}
+
+func (p *DiscoveryPublisher) ActorAdded(actor *eltako.ShadingActor) {
+	data, err := json.Marshal(p.coverConfig(actor))
+	if err != nil {
+		logger.Error("Failed to marshal Home Assistant discovery config", actor, err)
+		return
+	}
+
+	logger.Debug("Publishing Home Assistant discovery config", actor)
+	mqtt.PublishAbsolute(p.configTopic(objectId(actor.Serial, actor.Name)), string(data), true)
+}
+
+// PurgeStale subscribes to the retained discovery configs and clears the ones
+// published by this gateway for devices that are no longer configured.
+func (p *DiscoveryPublisher) PurgeStale() {
+	configured := make(map[string]bool)
+	for _, device := range p.devices {
+		configured[uniqueIdPrefix+objectId(device.Serial, device.Name)] = true
+	}
+
+	mqtt.Subscribe(p.configTopic("+"), func(topic string, payload []byte) {
+		if len(payload) == 0 {
+			return
+		}
+
+		var cover CoverConfig
+		if err := json.Unmarshal(payload, &cover); err != nil {
+			return
+		}
+
+		if !strings.HasPrefix(cover.UniqueId, uniqueIdPrefix) ||
+			!strings.HasPrefix(cover.PositionTopic, p.topic+"/") ||
+			configured[cover.UniqueId] {
+			return
+		}
+
+		logger.Info("Removing stale Home Assistant discovery config", topic)
+		// Publishing waits for the broker, which must not happen in the
+		// message handler of the client
+		go mqtt.PublishAbsolute(topic, "", true)
+	})
+}
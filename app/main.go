@@ -1,25 +1,57 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/mqtt-home/eltako-to-mqtt-gw/web"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
-	"sync"
 	"syscall"
+	// Schedules are evaluated in the local time zone given by TZ
+	_ "time/tzdata"
 
 	"github.com/mqtt-home/eltako-to-mqtt-gw/commands"
 	"github.com/mqtt-home/eltako-to-mqtt-gw/config"
 	"github.com/mqtt-home/eltako-to-mqtt-gw/discovery"
 	"github.com/mqtt-home/eltako-to-mqtt-gw/eltako"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/health"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/homeassistant"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/protection"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/scenes"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/scheduler"
 	"github.com/philipparndt/go-logger"
 	"github.com/philipparndt/mqtt-gateway/mqtt"
 )
 
-func startActors(cfg config.Eltako) {
+// openStateStore restores the actor states persisted before the last
+// shutdown. A broken file is logged and replaced instead of preventing the start.
+func openStateStore(cfg config.Config) {
+	file := filepath.Join(cfg.DataDir, "actors.json")
+	store, err := eltako.OpenStateStore(file)
+	if err != nil {
+		logger.Warn("Ignoring stored actor states", err)
+		store = eltako.NewStateStore(file)
+	}
+	eltako.Persisted = store
+	store.Start()
+}
 
-	wg := &sync.WaitGroup{}
+// startHistory records the position changes and commands of all actors in
+// history.json in the data directory
+func startHistory(cfg config.Config) {
+	file := filepath.Join(cfg.DataDir, "history.json")
+	history, err := eltako.OpenHistoryStore(file, cfg.History.MaxEntries)
+	if err != nil {
+		logger.Warn("Ignoring stored history", err)
+		history = eltako.NewHistoryStore(file, cfg.History.MaxEntries)
+	}
+	eltako.History = history
+	history.Start(eltako.Events)
+}
+
+func startActors(cfg config.Eltako) {
 	for _, device := range cfg.Devices {
 		if device.Ip == "" && device.Serial == "" {
 			logger.Warn("Skipping actor because neither IP nor serial number is defined", device.Name)
@@ -29,22 +61,28 @@ func startActors(cfg config.Eltako) {
 			continue
 		}
 
-		startActor(&device, cfg.PollingInterval, wg)
+		startActor(&device, eltako.DefaultModel)
 	}
-	wg.Wait()
 }
 
-func startActor(device *config.Device, pollingInterval int, wg *sync.WaitGroup) *eltako.ShadingActor {
+func startActor(device *config.Device, model string) *eltako.ShadingActor {
 	logger.Info(fmt.Sprintf("Initializing actor: %s", device.Name), device.Ip)
 	actor := eltako.NewShadingActor(*device)
-	err := actor.Start(wg, pollingInterval)
-	if err != nil {
-		panic(err)
+	if model != "" {
+		actor.Model = model
 	}
+	actor.Start()
 	registry.AddActor(actor)
 	return actor
 }
 
+// rejectMQTTCommand counts a command that was rejected before it reached an actor
+func rejectMQTTCommand(reason string) {
+	source := string(commands.SourceMQTT)
+	eltako.CommandsReceived.WithLabelValues(source).Inc()
+	eltako.CommandsRejected.WithLabelValues(source, reason).Inc()
+}
+
 func subscribeToCommands(cfg config.Config, actors *eltako.ActorRegistry) {
 	prefix := cfg.MQTT.Topic + "/"
 	postfix := "/set"
@@ -53,16 +91,152 @@ func subscribeToCommands(cfg config.Config, actors *eltako.ActorRegistry) {
 		actor := actors.GetActor(topic[len(prefix) : len(topic)-len(postfix)])
 		if actor == nil {
 			logger.Error("Unknown actor:", topic)
+			rejectMQTTCommand(eltako.RejectedUnknownActor)
 			return
 		}
 
 		command, err := commands.Parse(payload)
 		if err != nil {
 			logger.Error("Failed to parse command", err)
+			rejectMQTTCommand(eltako.RejectedInvalid)
+			return
+		}
+		command.Source = commands.SourceMQTT
+		id, err := actor.Submit(command)
+		if err != nil {
+			logger.Warn("Command refused", id, err)
+			return
+		}
+		logger.Debug("Command submitted", id, command.RequestID)
+	})
+}
+
+func registerGroups(cfg config.Config, actors *eltako.ActorRegistry) {
+	for _, group := range cfg.Groups {
+		logger.Info("Registering group", group.Name, group.Actors)
+		actors.AddGroup(eltako.NewActorGroup(group.Name, group.Actors, actors))
+	}
+}
+
+func subscribeToGroupCommands(cfg config.Config, actors *eltako.ActorRegistry) {
+	prefix := cfg.MQTT.Topic + "/group/"
+	postfix := "/set"
+	mqtt.Subscribe(prefix+"+"+postfix, func(topic string, payload []byte) {
+		logger.Debug("Received message", topic, string(payload))
+		group := actors.GetGroup(topic[len(prefix) : len(topic)-len(postfix)])
+		if group == nil {
+			logger.Error("Unknown group:", topic)
+			rejectMQTTCommand(eltako.RejectedUnknownGroup)
+			return
+		}
+
+		command, err := commands.Parse(payload)
+		if err != nil {
+			logger.Error("Failed to parse command", err)
+			rejectMQTTCommand(eltako.RejectedInvalid)
+			return
+		}
+		command.Source = commands.SourceMQTT
+		go group.Apply(command)
+	})
+}
+
+func startScenes(cfg config.Config, actors *eltako.ActorRegistry) *scenes.Manager {
+	manager, err := scenes.NewManager(cfg, actors)
+	if err != nil {
+		logger.Error("Failed to load scenes", err)
+		os.Exit(1)
+	}
+	manager.OnReport = func(report *scenes.Report) {
+		mqtt.PublishJSON("scene/"+report.Scene+"/result", report)
+	}
+
+	prefix := cfg.MQTT.Topic + "/scene/"
+	postfix := "/set"
+	mqtt.Subscribe(prefix+"+"+postfix, func(topic string, payload []byte) {
+		logger.Debug("Received message", topic, string(payload))
+		name := topic[len(prefix) : len(topic)-len(postfix)]
+		go func() {
+			_, err := manager.Activate(name)
+			if err != nil {
+				logger.Error("Failed to activate scene", err)
+			}
+		}()
+	})
+
+	return manager
+}
+
+type scheduleCommand struct {
+	Enabled *bool `json:"enabled"`
+}
+
+func startScheduler(cfg config.Config, actors *eltako.ActorRegistry, sceneManager *scenes.Manager) *scheduler.Scheduler {
+	s, err := scheduler.New(cfg, actors, sceneManager)
+	if err != nil {
+		logger.Error("Failed to load schedules", err)
+		os.Exit(1)
+	}
+	s.OnChange = func(status scheduler.Status) {
+		mqtt.PublishJSON("schedule/"+status.Name, status)
+	}
+
+	prefix := cfg.MQTT.Topic + "/schedule/"
+	postfix := "/set"
+	mqtt.Subscribe(prefix+"+"+postfix, func(topic string, payload []byte) {
+		logger.Debug("Received message", topic, string(payload))
+		name := topic[len(prefix) : len(topic)-len(postfix)]
+
+		var command scheduleCommand
+		if err := json.Unmarshal(payload, &command); err != nil || command.Enabled == nil {
+			logger.Error("Failed to parse schedule command", string(payload))
 			return
 		}
-		go actor.Apply(command)
+		go func() {
+			if err := s.SetEnabled(name, *command.Enabled); err != nil {
+				logger.Error("Failed to update schedule", err)
+			}
+		}()
 	})
+
+	s.Start()
+	for _, status := range s.List() {
+		mqtt.PublishJSON("schedule/"+status.Name, status)
+	}
+
+	return s
+}
+
+func startProtection(cfg config.Config, actors *eltako.ActorRegistry) {
+	manager, err := protection.NewManager(cfg, actors)
+	if err != nil {
+		logger.Error("Failed to configure protection", err)
+		os.Exit(1)
+	}
+
+	for _, topic := range manager.Topics() {
+		logger.Info("Subscribing to protection sensor", topic)
+		mqtt.Subscribe(topic, func(topic string, payload []byte) {
+			logger.Debug("Received message", topic, string(payload))
+			manager.Update(topic, payload)
+		})
+	}
+}
+
+func startInterlocks(cfg config.Config, actors *eltako.ActorRegistry) {
+	interlocks, err := protection.NewInterlocks(cfg.Eltako, actors)
+	if err != nil {
+		logger.Error("Failed to configure interlocks", err)
+		os.Exit(1)
+	}
+
+	for _, topic := range interlocks.Topics() {
+		logger.Info("Subscribing to interlock contact", topic)
+		mqtt.Subscribe(topic, func(topic string, payload []byte) {
+			logger.Debug("Received message", topic, string(payload))
+			interlocks.Update(topic, payload)
+		})
+	}
 }
 
 func startDiscovery(cfg config.Config) {
@@ -82,32 +256,39 @@ func startDiscovery(cfg config.Config) {
 	actorUpdates := make(chan discovery.ActorEvent, 1)
 	d := discovery.New(actorUpdates)
 	d.Start()
+	health.Register("discovery", d.Check)
 
 	go func() {
 		for event := range actorUpdates {
 			a := event.Actor
-			if event.Type == "added" {
+			switch event.Type {
+			case "added", "updated":
+				actor := registry.GetActorBySN(a.SN)
+				if actor != nil {
+					logger.Info("Actor announced", event.Type, a.Instance, a.Addr, a.SN)
+					actor.SetAddress(a.Addr)
+					continue
+				}
+
 				d := cfg.Eltako.GetBySN(a.SN)
 				if d == nil {
 					logger.Warn("Cannot register actor; no actor configured with serial number:", a.SN, a)
 				} else {
 					d.Ip = a.Addr
-					wg := &sync.WaitGroup{}
-					startActor(d, cfg.Eltako.PollingInterval, wg)
-					wg.Wait()
+					startActor(d, a.MD)
 				}
-			} else if event.Type == "updated" {
-				logger.Warn("Actor updated (operation not supported)", event.Type, a.Instance, a.Addr, a.Port, a.PN, a.SN, a.MD)
-				// currently not supported
-			} else if event.Type == "removed" {
-				logger.Warn("Actor removed (operation not supported)", event.Type, a.Instance, a.Addr, a.Port, a.PN, a.SN, a.MD)
-			} else {
+			case "removed":
+				actor := registry.GetActorBySN(a.SN)
+				// The actor may already have been re-announced with a new address
+				if actor != nil && actor.Address() == a.Addr {
+					logger.Warn("Actor removed, marking it offline", a.Instance, a.Addr, a.SN)
+					actor.MarkOffline()
+				}
+			default:
 				logger.Panic("Unknown event type:", event.Type, a.Instance, a.Addr, a.Port, a.PN, a.SN, a.MD)
 			}
-
 		}
 	}()
-
 }
 
 var registry = eltako.NewActorRegistry()
@@ -118,6 +299,10 @@ func main() {
 		os.Exit(1)
 	}
 
+	if os.Args[1] == "healthcheck" {
+		os.Exit(healthcheck(os.Args[2:]))
+	}
+
 	configFile := os.Args[1]
 	logger.Info("Configuration file:", configFile)
 	err := error(nil)
@@ -129,20 +314,41 @@ func main() {
 	}
 
 	logger.SetLevel(cfg.LogLevel)
-
-	startDiscovery(cfg)
+	openStateStore(cfg)
+	startHistory(cfg)
 
 	mqtt.Start(cfg.MQTT, "eltako_mqtt")
+	health.StartMQTTHeartbeat(cfg.MQTT.Topic + "/bridge/heartbeat")
+	health.Register("actors", func() (any, error) {
+		return registry.Initialized(cfg.Eltako.Devices)
+	})
+	registerGroups(cfg, registry)
+	eltako.StartMQTTPublisher(eltako.Events, registry)
+	eltako.RegisterActorMetrics(registry)
+
+	if cfg.HomeAssistant.Enabled {
+		logger.Info("Home Assistant discovery enabled with prefix", cfg.HomeAssistant.DiscoveryPrefix)
+		publisher := homeassistant.NewDiscoveryPublisher(cfg)
+		registry.AddListener(publisher)
+		publisher.PurgeStale()
+	}
+
+	startProtection(cfg, registry)
+	startInterlocks(cfg, registry)
+	startDiscovery(cfg)
 
 	startActors(cfg.Eltako)
 	subscribeToCommands(cfg, registry)
+	subscribeToGroupCommands(cfg, registry)
+	sceneManager := startScenes(cfg, registry)
+	schedules := startScheduler(cfg, registry, sceneManager)
 
 	// Start web server
 	if !cfg.Web.Enabled {
 		logger.Info("Web interface is disabled in the configuration")
 	} else {
 		logger.Info("Web interface enabled, starting web server")
-		webServer := web.NewWebServer(registry)
+		webServer := web.NewWebServer(registry, sceneManager, schedules, cfg.Web)
 		go func() {
 			err := webServer.Start(cfg.Web.Port)
 			if err != nil {
@@ -157,4 +363,7 @@ func main() {
 	<-quitChannel
 
 	logger.Info("Received quit signal")
+	eltako.History.Flush()
+	eltako.Persisted.Flush()
+	mqtt.PublishAbsolute(cfg.MQTT.Topic+"/bridge/state", "offline", true)
 }
@@ -0,0 +1,23 @@
+// Package metrics holds the Prometheus registry of the gateway. The metrics
+// are declared once at package level of the packages that update them.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry only holds the metrics of the gateway, without the Go runtime
+// and process collectors of the default Prometheus registry
+var Registry = prometheus.NewRegistry()
+
+// Factory creates metrics that are registered with Registry
+var Factory = promauto.With(Registry)
+
+// Handler serves the metrics of the registry
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
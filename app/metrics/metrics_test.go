@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func scrape(t *testing.T) string {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	return recorder.Body.String()
+}
+
+func expectLines(t *testing.T, output string, lines ...string) {
+	t.Helper()
+
+	for _, line := range lines {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("expected line %q in output:\n%s", line, output)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	requests := Factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "test_requests_total",
+		Help: "Requests",
+	}, []string{"method", "code"})
+	requests.WithLabelValues("GET", "200").Inc()
+	requests.WithLabelValues("GET", "200").Inc()
+	Factory.NewGaugeFunc(prometheus.GaugeOpts{Name: "test_clients", Help: "Clients"}, func() float64 {
+		return 4
+	})
+
+	expectLines(t, scrape(t),
+		"# HELP test_requests_total Requests",
+		"# TYPE test_requests_total counter",
+		`test_requests_total{code="200",method="GET"} 2`,
+		"# TYPE test_clients gauge",
+		"test_clients 4",
+	)
+	if strings.Contains(scrape(t), "go_goroutines") {
+		t.Error("expected no Go runtime metrics")
+	}
+}
@@ -0,0 +1,88 @@
+package protection
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/config"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/eltako"
+	"github.com/philipparndt/go-logger"
+)
+
+// Interlocks keeps the state of the window and door contacts of the actors.
+// Contacts may report before the actor is registered, the state is applied
+// once it is.
+type Interlocks struct {
+	registry *eltako.ActorRegistry
+	devices  map[string]*config.Interlock
+	open     map[string]bool
+	mu       sync.Mutex
+}
+
+func NewInterlocks(cfg config.Eltako, registry *eltako.ActorRegistry) (*Interlocks, error) {
+	i := &Interlocks{
+		registry: registry,
+		devices:  make(map[string]*config.Interlock),
+		open:     make(map[string]bool),
+	}
+
+	for _, device := range cfg.Devices {
+		if device.Interlock == nil {
+			continue
+		}
+		if device.Interlock.Topic == "" || device.Interlock.OpenPayload == "" {
+			return nil, fmt.Errorf("interlock of actor '%s' requires a topic and an openPayload", device.Name)
+		}
+		i.devices[strings.ToLower(device.Name)] = device.Interlock
+	}
+
+	registry.AddListener(i)
+	return i, nil
+}
+
+// Update processes a message published on the topic of one or more contacts
+func (i *Interlocks) Update(topic string, payload []byte) {
+	for name, interlock := range i.devices {
+		if interlock.Topic != topic {
+			continue
+		}
+
+		value, err := extract(payload, interlock.Property)
+		if err != nil {
+			logger.Error("Failed to parse contact state", name, err)
+			continue
+		}
+		open := strings.EqualFold(fmt.Sprint(value), interlock.OpenPayload)
+
+		i.mu.Lock()
+		i.open[name] = open
+		i.mu.Unlock()
+
+		if actor := i.registry.GetActor(name); actor != nil {
+			actor.SetContactOpen(open)
+		}
+	}
+}
+
+func (i *Interlocks) ActorAdded(actor *eltako.ShadingActor) {
+	i.mu.Lock()
+	open, ok := i.open[strings.ToLower(actor.Name)]
+	i.mu.Unlock()
+
+	if ok {
+		actor.SetContactOpen(open)
+	}
+}
+
+// Topics returns the MQTT topics of the contacts
+func (i *Interlocks) Topics() []string {
+	var topics []string
+	for _, interlock := range i.devices {
+		if !slices.Contains(topics, interlock.Topic) {
+			topics = append(topics, interlock.Topic)
+		}
+	}
+	return topics
+}
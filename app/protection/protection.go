@@ -0,0 +1,239 @@
+package protection
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/commands"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/config"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/eltako"
+	"github.com/philipparndt/go-logger"
+)
+
+type sensor struct {
+	config.WeatherSensor
+	command commands.LLCommand
+	holdOff time.Duration
+	locked  bool
+	reason  string
+	release *time.Timer
+	// generation invalidates release timers that fire after the value
+	// exceeded the threshold again
+	generation int
+}
+
+func (s *sensor) owner() string {
+	return "protection/" + s.Name
+}
+
+func (s *sensor) affects(actor *eltako.ShadingActor) bool {
+	if len(s.Actors) == 0 {
+		return true
+	}
+	for _, name := range s.Actors {
+		if strings.EqualFold(name, actor.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Manager locks actors while a weather sensor reports values above its
+// threshold, e.g. high wind or rain, and drives them to a safe position
+type Manager struct {
+	registry *eltako.ActorRegistry
+	sensors  map[string]*sensor
+	mu       sync.Mutex
+}
+
+func NewManager(cfg config.Config, registry *eltako.ActorRegistry) (*Manager, error) {
+	m := &Manager{
+		registry: registry,
+		sensors:  make(map[string]*sensor),
+	}
+
+	for _, s := range cfg.Protection {
+		if s.Name == "" || s.Topic == "" {
+			return nil, fmt.Errorf("protection sensors require a name and a topic")
+		}
+		if _, ok := m.sensors[s.Name]; ok {
+			return nil, fmt.Errorf("duplicate protection sensor '%s'", s.Name)
+		}
+		command, err := s.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("invalid safe position for sensor '%s': %w", s.Name, err)
+		}
+		command.Source = commands.SourceProtection
+
+		m.sensors[s.Name] = &sensor{
+			WeatherSensor: s,
+			command:       command,
+			holdOff:       time.Duration(s.HoldOff) * time.Millisecond,
+		}
+	}
+
+	registry.AddListener(m)
+	return m, nil
+}
+
+// Update processes a message published on the topic of one or more sensors
+func (m *Manager) Update(topic string, payload []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range m.sensors {
+		if s.Topic != topic {
+			continue
+		}
+
+		value, err := parseValue(payload, s.Property)
+		if err != nil {
+			logger.Error("Failed to parse protection sensor value", s.Name, err)
+			continue
+		}
+		logger.Debug("Protection sensor value", s.Name, value)
+		m.update(s, value)
+	}
+}
+
+// update must be called with the lock held
+func (m *Manager) update(s *sensor, value float64) {
+	if value > s.Threshold {
+		s.generation++
+		if s.release != nil {
+			s.release.Stop()
+			s.release = nil
+		}
+		if !s.locked {
+			s.locked = true
+			s.reason = fmt.Sprintf("%s protection: %g exceeds %g", s.Name, value, s.Threshold)
+			logger.Warn("Protection triggered", s.reason)
+			for _, actor := range m.affectedActors(s) {
+				m.lock(s, actor)
+			}
+		}
+		return
+	}
+
+	if s.locked && s.release == nil {
+		logger.Info("Protection value below threshold, releasing after hold-off", s.Name, s.holdOff)
+		generation := s.generation
+		s.release = time.AfterFunc(s.holdOff, func() {
+			m.releaseSensor(s, generation)
+		})
+	}
+}
+
+func (m *Manager) releaseSensor(s *sensor, generation int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if s.generation != generation || !s.locked {
+		return
+	}
+
+	logger.Info("Protection released", s.Name)
+	s.locked = false
+	s.release = nil
+	for _, actor := range m.affectedActors(s) {
+		actor.Unlock(s.owner())
+	}
+}
+
+func (m *Manager) affectedActors(s *sensor) []*eltako.ShadingActor {
+	var actors []*eltako.ShadingActor
+	for _, actor := range m.registry.AllActors() {
+		if s.affects(actor) {
+			actors = append(actors, actor)
+		}
+	}
+	return actors
+}
+
+// lock must be called with the lock held
+func (m *Manager) lock(s *sensor, actor *eltako.ShadingActor) {
+	actor.Lock(s.owner(), s.reason)
+	go func() {
+		if err := actor.Apply(s.command); err != nil {
+			logger.Error("Failed to drive actor to safe position", actor.Name, err)
+		}
+	}()
+}
+
+// ActorAdded locks actors that are registered while a sensor is triggered
+func (m *Manager) ActorAdded(actor *eltako.ShadingActor) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range m.sensors {
+		if s.locked && s.affects(actor) {
+			m.lock(s, actor)
+		}
+	}
+}
+
+// Topics returns the MQTT topics of the sensors
+func (m *Manager) Topics() []string {
+	var topics []string
+	for _, s := range m.sensors {
+		if !slices.Contains(topics, s.Topic) {
+			topics = append(topics, s.Topic)
+		}
+	}
+	return topics
+}
+
+// extract returns the value at the property path of a JSON payload. Payloads
+// that are not JSON are returned as string.
+func extract(payload []byte, property string) (any, error) {
+	var value any
+	if err := json.Unmarshal(payload, &value); err != nil {
+		value = strings.TrimSpace(string(payload))
+	}
+
+	if property != "" {
+		for _, key := range strings.Split(property, ".") {
+			object, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("property '%s' not found", property)
+			}
+			if value, ok = object[key]; !ok {
+				return nil, fmt.Errorf("property '%s' not found", property)
+			}
+		}
+	}
+	return value, nil
+}
+
+// parseValue reads a number from the payload. Booleans and payloads like
+// ON/OFF are mapped to 1 and 0, so rain sensors work with a threshold of 0.
+func parseValue(payload []byte, property string) (float64, error) {
+	value, err := extract(payload, property)
+	if err != nil {
+		return 0, err
+	}
+
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		switch strings.ToLower(v) {
+		case "on", "true", "yes":
+			return 1, nil
+		case "off", "false", "no":
+			return 0, nil
+		}
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, fmt.Errorf("unsupported value %v", value)
+}
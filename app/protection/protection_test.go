@@ -0,0 +1,93 @@
+package protection
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/commands"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/config"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/eltako"
+)
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		payload  string
+		property string
+		expected float64
+	}{
+		{"12.5", "", 12.5},
+		{`{"wind": {"speed": 18}}`, "wind.speed", 18},
+		{`{"rain": true}`, "rain", 1},
+		{"OFF", "", 0},
+		{"ON", "", 1},
+	}
+
+	for _, test := range tests {
+		value, err := parseValue([]byte(test.payload), test.property)
+		if err != nil {
+			t.Errorf("parseValue(%q, %q) failed: %v", test.payload, test.property, err)
+			continue
+		}
+		if value != test.expected {
+			t.Errorf("parseValue(%q, %q): expected %v, got %v", test.payload, test.property, test.expected, value)
+		}
+	}
+
+	if _, err := parseValue([]byte(`{"speed": 3}`), "gust"); err == nil {
+		t.Error("expected missing property to fail")
+	}
+}
+
+func TestLockAndRelease(t *testing.T) {
+	registry := eltako.NewActorRegistry()
+	manager, err := NewManager(config.Config{
+		Protection: []config.WeatherSensor{
+			{
+				Name:      "wind",
+				Topic:     "weather/wind",
+				Threshold: 15,
+				HoldOff:   50,
+				Actors:    []string{"terrace"},
+				Action:    commands.Action{Action: commands.ActionOpen},
+			},
+		},
+	}, registry)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+
+	terrace := eltako.NewShadingActor(config.Device{Name: "terrace", Ip: "127.0.0.1:1"})
+	kitchen := eltako.NewShadingActor(config.Device{Name: "kitchen", Ip: "127.0.0.1:1"})
+	registry.AddActor(terrace)
+	registry.AddActor(kitchen)
+
+	manager.Update("weather/wind", []byte("20"))
+	if !terrace.LockState().Locked {
+		t.Fatal("expected terrace to be locked")
+	}
+	if kitchen.LockState().Locked {
+		t.Error("expected kitchen not to be affected")
+	}
+
+	var locked *eltako.LockedError
+	err = terrace.Apply(commands.LLCommand{Action: commands.LLActionSet, Position: 0, Source: commands.SourceMQTT})
+	if !errors.As(err, &locked) {
+		t.Errorf("expected LockedError, got %v", err)
+	}
+
+	// Gusts during the hold-off time restart it
+	manager.Update("weather/wind", []byte("10"))
+	time.Sleep(30 * time.Millisecond)
+	manager.Update("weather/wind", []byte("16"))
+	manager.Update("weather/wind", []byte("10"))
+	time.Sleep(30 * time.Millisecond)
+	if !terrace.LockState().Locked {
+		t.Fatal("expected terrace to stay locked during hold-off")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if terrace.LockState().Locked {
+		t.Error("expected terrace to be released after hold-off")
+	}
+}
@@ -1,10 +1,23 @@
 package retry
 
 import (
+	"github.com/mqtt-home/eltako-to-mqtt-gw/metrics"
 	"github.com/philipparndt/go-logger"
+	"github.com/prometheus/client_golang/prometheus"
 	"time"
 )
 
+var (
+	retries = metrics.Factory.NewCounter(prometheus.CounterOpts{
+		Name: "eltako_retries_total",
+		Help: "Failed attempts that were retried",
+	})
+	exhausted = metrics.Factory.NewCounter(prometheus.CounterOpts{
+		Name: "eltako_retries_exhausted_total",
+		Help: "Operations that failed after all attempts",
+	})
+)
+
 func Times[T any](times int, f func() (T, error)) (T, error) {
 	current := 0
 	var zeroValue T // Zero value of the type T
@@ -16,10 +29,12 @@ func Times[T any](times int, f func() (T, error)) (T, error) {
 
 		current++
 		if current >= times {
+			exhausted.Inc()
 			logger.Error("Failed to execute after", times)
 			return zeroValue, err
 		}
 
+		retries.Inc()
 		logger.Error("Failed to execute, retrying: ", err)
 		time.Sleep(500 * time.Millisecond)
 	}
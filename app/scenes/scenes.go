@@ -0,0 +1,258 @@
+package scenes
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/commands"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/config"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/eltako"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/storage"
+	"github.com/philipparndt/go-logger"
+)
+
+const (
+	SourceConfig = "config"
+	SourceAPI    = "api"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusFailed   = "failed"
+	StatusNotFound = "notFound"
+)
+
+type Scene struct {
+	Name    string               `json:"name"`
+	Actions []config.SceneAction `json:"actions"`
+	// Source is "config" for read-only scenes from the configuration file
+	// and "api" for scenes created through the REST API
+	Source string `json:"source"`
+}
+
+type ActionResult struct {
+	Actor  string              `json:"actor"`
+	Action commands.ActionType `json:"action"`
+	Status string              `json:"status"`
+	Error  string              `json:"error,omitempty"`
+}
+
+type Report struct {
+	Scene      string         `json:"scene"`
+	StartedAt  time.Time      `json:"startedAt"`
+	FinishedAt time.Time      `json:"finishedAt"`
+	Success    bool           `json:"success"`
+	Results    []ActionResult `json:"results"`
+}
+
+// Manager holds the scenes from the configuration and the ones created
+// through the REST API. The latter are persisted in the data directory.
+type Manager struct {
+	// OnReport is called with the report of every scene activation
+	OnReport func(report *Report)
+
+	registry *eltako.ActorRegistry
+	file     string
+	scenes   map[string]*Scene
+	reports  map[string]*Report
+	mu       sync.Mutex
+}
+
+func NewManager(cfg config.Config, registry *eltako.ActorRegistry) (*Manager, error) {
+	m := &Manager{
+		registry: registry,
+		file:     filepath.Join(cfg.DataDir, "scenes.json"),
+		scenes:   make(map[string]*Scene),
+		reports:  make(map[string]*Report),
+	}
+
+	var stored []*Scene
+	if err := storage.ReadJSON(m.file, &stored); err != nil {
+		return nil, fmt.Errorf("failed to read scenes from %s: %w", m.file, err)
+	}
+	for _, scene := range stored {
+		scene.Source = SourceAPI
+		m.scenes[strings.ToLower(scene.Name)] = scene
+	}
+
+	// Scenes from the configuration take precedence
+	for _, scene := range cfg.Scenes {
+		m.scenes[strings.ToLower(scene.Name)] = &Scene{
+			Name:    scene.Name,
+			Actions: scene.Actions,
+			Source:  SourceConfig,
+		}
+	}
+
+	return m, nil
+}
+
+func (m *Manager) List() []Scene {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := []Scene{}
+	for _, scene := range m.scenes {
+		result = append(result, *scene)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+func (m *Manager) Get(name string) (Scene, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	scene, ok := m.scenes[strings.ToLower(name)]
+	if !ok {
+		return Scene{}, false
+	}
+	return *scene, true
+}
+
+func (m *Manager) LastReport(name string) *Report {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.reports[strings.ToLower(name)]
+}
+
+// Put creates or replaces a scene. Scenes from the configuration cannot be changed.
+func (m *Manager) Put(scene Scene) error {
+	if scene.Name == "" {
+		return fmt.Errorf("scene name must not be empty")
+	}
+	for _, action := range scene.Actions {
+		if action.Actor == "" {
+			return fmt.Errorf("actor must not be empty")
+		}
+		if _, err := action.Validate(); err != nil {
+			return fmt.Errorf("invalid action '%s' for actor %s", action.Action.Action, action.Actor)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := strings.ToLower(scene.Name)
+	if existing, ok := m.scenes[key]; ok && existing.Source == SourceConfig {
+		return fmt.Errorf("scene '%s' is defined in the configuration and cannot be changed", scene.Name)
+	}
+
+	scene.Source = SourceAPI
+	m.scenes[key] = &scene
+	return m.save()
+}
+
+// Delete removes a scene. Scenes from the configuration cannot be deleted.
+func (m *Manager) Delete(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := strings.ToLower(name)
+	scene, ok := m.scenes[key]
+	if !ok {
+		return fmt.Errorf("scene '%s' not found", name)
+	}
+	if scene.Source == SourceConfig {
+		return fmt.Errorf("scene '%s' is defined in the configuration and cannot be deleted", name)
+	}
+
+	delete(m.scenes, key)
+	delete(m.reports, key)
+	return m.save()
+}
+
+// save must be called with the lock held
+func (m *Manager) save() error {
+	var stored []*Scene
+	for _, scene := range m.scenes {
+		if scene.Source == SourceAPI {
+			stored = append(stored, scene)
+		}
+	}
+	sort.Slice(stored, func(i, j int) bool {
+		return stored[i].Name < stored[j].Name
+	})
+
+	return storage.WriteJSON(m.file, stored)
+}
+
+// Activate executes all actions of the scene in parallel and waits until
+// they are done
+func (m *Manager) Activate(name string) (*Report, error) {
+	scene, ok := m.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("scene '%s' not found", name)
+	}
+
+	logger.Info("Activating scene", scene.Name)
+	report := &Report{
+		Scene:     scene.Name,
+		StartedAt: time.Now(),
+		Success:   true,
+		Results:   make([]ActionResult, len(scene.Actions)),
+	}
+
+	wg := sync.WaitGroup{}
+	for i, action := range scene.Actions {
+		wg.Add(1)
+		go func(i int, action config.SceneAction) {
+			defer wg.Done()
+			report.Results[i] = m.apply(action)
+		}(i, action)
+	}
+	wg.Wait()
+
+	report.FinishedAt = time.Now()
+	for _, result := range report.Results {
+		if result.Status != StatusOK {
+			report.Success = false
+		}
+	}
+
+	m.mu.Lock()
+	m.reports[strings.ToLower(scene.Name)] = report
+	m.mu.Unlock()
+
+	logger.Info("Scene activated", scene.Name, "success:", report.Success)
+	if m.OnReport != nil {
+		m.OnReport(report)
+	}
+	return report, nil
+}
+
+func (m *Manager) apply(action config.SceneAction) ActionResult {
+	result := ActionResult{
+		Actor:  action.Actor,
+		Action: action.Action.Action,
+		Status: StatusOK,
+	}
+
+	actor := m.registry.GetActor(action.Actor)
+	if actor == nil {
+		result.Status = StatusNotFound
+		result.Error = fmt.Sprintf("actor '%s' not found", action.Actor)
+		return result
+	}
+
+	command, err := action.Validate()
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+		return result
+	}
+	command.Source = commands.SourceScene
+
+	if err := actor.Apply(command); err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,100 @@
+package scenes
+
+import (
+	"testing"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/commands"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/config"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/eltako"
+)
+
+func newTestManager(t *testing.T, dataDir string) *Manager {
+	t.Helper()
+
+	cfg := config.Config{
+		DataDir: dataDir,
+		Scenes: []config.Scene{
+			{
+				Name: "morning",
+				Actions: []config.SceneAction{
+					{Actor: "living room", Action: commands.Action{Action: commands.ActionOpen}},
+				},
+			},
+		},
+	}
+
+	manager, err := NewManager(cfg, eltako.NewActorRegistry())
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	return manager
+}
+
+func TestScenesArePersisted(t *testing.T) {
+	dir := t.TempDir()
+	manager := newTestManager(t, dir)
+
+	err := manager.Put(Scene{
+		Name: "movie night",
+		Actions: []config.SceneAction{
+			{Actor: "living room", Action: commands.Action{Action: commands.ActionClose}},
+			{Actor: "kitchen", Action: commands.Action{Action: commands.ActionTilt, Position: 30}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	scene, ok := newTestManager(t, dir).Get("Movie Night")
+	if !ok {
+		t.Fatal("expected scene to be restored")
+	}
+	if scene.Source != SourceAPI || len(scene.Actions) != 2 || scene.Actions[1].Position != 30 {
+		t.Errorf("unexpected scene %+v", scene)
+	}
+}
+
+func TestConfiguredScenesAreReadOnly(t *testing.T) {
+	manager := newTestManager(t, t.TempDir())
+
+	if err := manager.Put(Scene{Name: "Morning"}); err == nil {
+		t.Error("expected configured scene not to be replaced")
+	}
+	if err := manager.Delete("morning"); err == nil {
+		t.Error("expected configured scene not to be deleted")
+	}
+}
+
+func TestPutRejectsInvalidActions(t *testing.T) {
+	manager := newTestManager(t, t.TempDir())
+
+	err := manager.Put(Scene{
+		Name: "broken",
+		Actions: []config.SceneAction{
+			{Actor: "kitchen", Action: commands.Action{Action: "jump"}},
+		},
+	})
+	if err == nil {
+		t.Error("expected invalid action to be rejected")
+	}
+}
+
+func TestActivateReportsUnknownActors(t *testing.T) {
+	manager := newTestManager(t, t.TempDir())
+
+	var published *Report
+	manager.OnReport = func(report *Report) {
+		published = report
+	}
+
+	report, err := manager.Activate("morning")
+	if err != nil {
+		t.Fatalf("Activate failed: %v", err)
+	}
+	if report.Success || len(report.Results) != 1 || report.Results[0].Status != StatusNotFound {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if published != report || manager.LastReport("Morning") != report {
+		t.Error("expected report to be published and kept")
+	}
+}
@@ -0,0 +1,58 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	// Wednesday
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2025, 1, 1, 12, 1, 0, 0, time.UTC)},
+		{"0 7 * * mon-fri", time.Date(2025, 1, 2, 7, 0, 0, 0, time.UTC)},
+		{"0 7 * * sat,sun", time.Date(2025, 1, 4, 7, 0, 0, 0, time.UTC)},
+		{"*/20 13 * * *", time.Date(2025, 1, 1, 13, 0, 0, 0, time.UTC)},
+		{"30 8 15 * *", time.Date(2025, 1, 15, 8, 30, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 9 1 * 5", time.Date(2025, 1, 3, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"5/15 12 * * *", time.Date(2025, 1, 1, 12, 5, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		cron, err := ParseCron(test.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q) failed: %v", test.expr, err)
+		}
+		if next := cron.Next(start); !next.Equal(test.expected) {
+			t.Errorf("%q: expected %v, got %v", test.expr, test.expected, next)
+		}
+	}
+}
+
+func TestCronInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "* * * foo *"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("expected %q to be rejected", expr)
+		}
+	}
+}
+
+func TestCronNextAcrossDaylightSavingTime(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone data not available")
+	}
+
+	cron, _ := ParseCron("0 7 * * *")
+	next := cron.Next(time.Date(2025, 3, 29, 12, 0, 0, 0, loc))
+	if expected := time.Date(2025, 3, 30, 7, 0, 0, 0, loc); !next.Equal(expected) {
+		t.Errorf("expected %v, got %v", expected, next)
+	}
+}
@@ -0,0 +1,148 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/commands"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/config"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/solar"
+	"github.com/philipparndt/go-logger"
+)
+
+// sunRule tracks for each actor whether the sun shines on its facade
+type sunRule struct {
+	config.SunRule
+	facades map[string]float64
+	inSun   map[string]bool
+}
+
+type SunRuleStatus struct {
+	Rule          string  `json:"rule"`
+	Actor         string  `json:"actor"`
+	FacadeAzimuth float64 `json:"facadeAzimuth"`
+	InSun         bool    `json:"inSun"`
+}
+
+type SunStatus struct {
+	Azimuth   float64         `json:"azimuth"`
+	Elevation float64         `json:"elevation"`
+	Sunrise   *time.Time      `json:"sunrise,omitempty"`
+	Sunset    *time.Time      `json:"sunset,omitempty"`
+	Rules     []SunRuleStatus `json:"rules"`
+}
+
+func newSunRule(rule config.SunRule, eltako config.Eltako) (*sunRule, error) {
+	if rule.Name == "" {
+		return nil, fmt.Errorf("name must not be empty")
+	}
+	if _, err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	if rule.Release != nil {
+		if _, err := rule.Release.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid release: %w", err)
+		}
+	}
+
+	r := &sunRule{
+		SunRule: rule,
+		facades: make(map[string]float64),
+		inSun:   make(map[string]bool),
+	}
+	for _, name := range rule.Actors {
+		device := eltako.GetByName(name)
+		if device == nil {
+			return nil, fmt.Errorf("actor '%s' not found", name)
+		}
+		if device.FacadeAzimuth == nil {
+			return nil, fmt.Errorf("actor '%s' has no facadeAzimuth", name)
+		}
+		r.facades[name] = *device.FacadeAzimuth
+	}
+	return r, nil
+}
+
+func (r *sunRule) shines(actor string, azimuth, elevation float64) bool {
+	return elevation >= r.MinElevation && solar.AngleDiff(azimuth, r.facades[actor]) <= r.AzimuthTolerance
+}
+
+// evaluateRules applies the commands of the sun rules whose state changed.
+// When the gateway starts, actors in the sun get the command of the rule,
+// but the release command is only sent after the sun has left the facade.
+func (s *Scheduler) evaluateRules(now time.Time) {
+	if s.location == nil || len(s.rules) == 0 {
+		return
+	}
+
+	azimuth, elevation := s.location.Position(now)
+	logger.Trace("Sun position", "azimuth:", azimuth, "elevation:", elevation)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, rule := range s.rules {
+		for _, actor := range rule.Actors {
+			shines := rule.shines(actor, azimuth, elevation)
+			previous, known := rule.inSun[actor]
+			rule.inSun[actor] = shines
+
+			switch {
+			case shines && !previous:
+				logger.Info("Sun shines on the facade", rule.Name, actor)
+				go s.applyRule(actor, rule.Action)
+			case !shines && previous && known && rule.Release != nil:
+				logger.Info("Sun left the facade", rule.Name, actor)
+				go s.applyRule(actor, *rule.Release)
+			}
+		}
+	}
+}
+
+func (s *Scheduler) applyRule(name string, action commands.Action) {
+	command, err := action.Validate()
+	if err != nil {
+		logger.Error("Invalid sun rule action", err)
+		return
+	}
+	command.Source = commands.SourceSchedule
+
+	actor := s.registry.GetActor(name)
+	if actor == nil {
+		logger.Warn("Sun rule actor not available", name)
+		return
+	}
+	if err := actor.Apply(command); err != nil {
+		logger.Error("Failed to apply sun rule", name, err)
+	}
+}
+
+// Sun returns the current position of the sun and the state of the sun
+// rules. The second result is false if no location is configured.
+func (s *Scheduler) Sun() (SunStatus, bool) {
+	if s.location == nil {
+		return SunStatus{}, false
+	}
+
+	now := s.now()
+	status := SunStatus{Rules: []SunRuleStatus{}}
+	status.Azimuth, status.Elevation = s.location.Position(now)
+	if sunrise, ok := s.location.Sunrise(now); ok {
+		status.Sunrise = &sunrise
+	}
+	if sunset, ok := s.location.Sunset(now); ok {
+		status.Sunset = &sunset
+	}
+
+	for _, rule := range s.rules {
+		for _, actor := range rule.Actors {
+			status.Rules = append(status.Rules, SunRuleStatus{
+				Rule:          rule.Name,
+				Actor:         actor,
+				FacadeAzimuth: rule.facades[actor],
+				InSun:         rule.shines(actor, status.Azimuth, status.Elevation),
+			})
+		}
+	}
+	return status, true
+}
@@ -0,0 +1,413 @@
+package scheduler
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/commands"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/config"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/eltako"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/scenes"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/solar"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/storage"
+	"github.com/philipparndt/go-logger"
+)
+
+const (
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+	RunSkipped   = "skipped"
+)
+
+const (
+	// ReasonDisabled is used for runs that were due while the schedule was disabled
+	ReasonDisabled = "disabled"
+	// ReasonMissed is used for runs that were due while the gateway was not running
+	ReasonMissed = "missed"
+)
+
+const (
+	// A run that is due for longer than this is not executed anymore
+	missedAfter    = time.Minute
+	maxSkippedRuns = 20
+	previewRuns    = 5
+)
+
+// Trigger computes the times at which a schedule is due
+type Trigger interface {
+	Next(after time.Time) time.Time
+}
+
+type Run struct {
+	Time   time.Time `json:"time"`
+	Status string    `json:"status"`
+	Reason string    `json:"reason,omitempty"`
+	Error  string    `json:"error,omitempty"`
+}
+
+type Status struct {
+	config.Schedule
+	Enabled     bool        `json:"enabled"`
+	NextRuns    []time.Time `json:"nextRuns"`
+	LastRun     *Run        `json:"lastRun,omitempty"`
+	SkippedRuns []Run       `json:"skippedRuns"`
+}
+
+// state is the part of a schedule that survives restarts
+type state struct {
+	// Enabled overrides the value from the configuration once a schedule
+	// was enabled or disabled through MQTT or the REST API
+	Enabled *bool `json:"enabled,omitempty"`
+	// LastDue is the last time the schedule was due, whether it ran or not
+	LastDue     time.Time `json:"lastDue"`
+	LastRun     *Run      `json:"lastRun,omitempty"`
+	SkippedRuns []Run     `json:"skippedRuns,omitempty"`
+}
+
+type entry struct {
+	config.Schedule
+	trigger Trigger
+	next    time.Time
+	state   *state
+}
+
+func (e *entry) enabled() bool {
+	if e.state.Enabled != nil {
+		return *e.state.Enabled
+	}
+	return e.Schedule.Enabled == nil || *e.Schedule.Enabled
+}
+
+// Scheduler applies commands to actors, groups and scenes at the times
+// configured in the schedules and evaluates the sun rules. Runs that are due while a schedule is
+// disabled or while the gateway is not running are recorded as skipped.
+type Scheduler struct {
+	// OnChange is called whenever a schedule ran, was skipped or was
+	// enabled or disabled
+	OnChange func(status Status)
+
+	registry *eltako.ActorRegistry
+	scenes   *scenes.Manager
+	file     string
+	entries  map[string]*entry
+	rules    []*sunRule
+	location *solar.Location
+	now      func() time.Time
+	mu       sync.Mutex
+}
+
+func New(cfg config.Config, registry *eltako.ActorRegistry, sceneManager *scenes.Manager) (*Scheduler, error) {
+	s := &Scheduler{
+		registry: registry,
+		scenes:   sceneManager,
+		file:     filepath.Join(cfg.DataDir, "schedules.json"),
+		entries:  make(map[string]*entry),
+		now:      time.Now,
+	}
+
+	stored := map[string]*state{}
+	if err := storage.ReadJSON(s.file, &stored); err != nil {
+		return nil, fmt.Errorf("failed to read schedules from %s: %w", s.file, err)
+	}
+
+	if cfg.Location != nil {
+		s.location = &solar.Location{
+			Latitude:  cfg.Location.Latitude,
+			Longitude: cfg.Location.Longitude,
+		}
+	}
+
+	for _, schedule := range cfg.Schedules {
+		trigger, err := validate(schedule, s.location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule '%s': %w", schedule.Name, err)
+		}
+
+		key := strings.ToLower(schedule.Name)
+		if _, ok := s.entries[key]; ok {
+			return nil, fmt.Errorf("duplicate schedule '%s'", schedule.Name)
+		}
+
+		st := stored[schedule.Name]
+		if st == nil {
+			st = &state{}
+		}
+		s.entries[key] = &entry{
+			Schedule: schedule,
+			trigger:  trigger,
+			state:    st,
+		}
+	}
+
+	for _, rule := range cfg.SunRules {
+		if s.location == nil {
+			return nil, fmt.Errorf("sun rule '%s' requires a location", rule.Name)
+		}
+		r, err := newSunRule(rule, cfg.Eltako)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sun rule '%s': %w", rule.Name, err)
+		}
+		s.rules = append(s.rules, r)
+	}
+
+	return s, nil
+}
+
+func validate(schedule config.Schedule, location *solar.Location) (Trigger, error) {
+	if schedule.Name == "" {
+		return nil, fmt.Errorf("name must not be empty")
+	}
+
+	targets := 0
+	for _, target := range []string{schedule.Actor, schedule.Group, schedule.Scene} {
+		if target != "" {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return nil, fmt.Errorf("exactly one of actor, group and scene must be set")
+	}
+
+	if schedule.Scene == "" {
+		if _, err := schedule.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case schedule.Cron != "" && schedule.Sun != "":
+		return nil, fmt.Errorf("cron and sun must not be set both")
+	case schedule.Sun != "":
+		if location == nil {
+			return nil, fmt.Errorf("sun requires a location")
+		}
+		return NewSunTrigger(*location, schedule.Sun, time.Duration(schedule.Offset)*time.Minute)
+	default:
+		return ParseCron(schedule.Cron)
+	}
+}
+
+// Start records the runs that were missed while the gateway was not
+// running and starts executing the schedules
+func (s *Scheduler) Start() {
+	now := s.now()
+
+	s.mu.Lock()
+	for _, e := range s.entries {
+		if e.state.LastDue.IsZero() {
+			e.next = e.trigger.Next(now)
+		} else {
+			e.next = e.trigger.Next(e.state.LastDue)
+		}
+	}
+	s.mu.Unlock()
+
+	s.tick(now)
+
+	go func() {
+		for {
+			now := s.now()
+			time.Sleep(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+			s.tick(s.now())
+		}
+	}()
+}
+
+func (s *Scheduler) tick(now time.Time) {
+	var changed []Status
+	var due []*entry
+	var dueTimes []time.Time
+
+	s.mu.Lock()
+	for _, e := range s.entries {
+		skipped := false
+		for !e.next.IsZero() && !e.next.After(now) {
+			e.state.LastDue = e.next
+			switch {
+			case e.next.Before(now.Add(-missedAfter)):
+				logger.Warn("Schedule missed", e.Name, e.next)
+				e.skip(e.next, ReasonMissed)
+				skipped = true
+			case !e.enabled():
+				logger.Info("Schedule skipped because it is disabled", e.Name)
+				e.skip(e.next, ReasonDisabled)
+				skipped = true
+			default:
+				due = append(due, e)
+				dueTimes = append(dueTimes, e.next)
+			}
+			e.next = e.trigger.Next(e.next)
+		}
+		if skipped {
+			changed = append(changed, s.status(e))
+		}
+	}
+	if len(changed) > 0 || len(due) > 0 {
+		s.save()
+	}
+	s.mu.Unlock()
+
+	s.notify(changed...)
+	for i, e := range due {
+		go s.fire(e, dueTimes[i])
+	}
+
+	s.evaluateRules(now)
+}
+
+// skip must be called with the lock held
+func (e *entry) skip(due time.Time, reason string) {
+	run := Run{Time: due, Status: RunSkipped, Reason: reason}
+	e.state.LastRun = &run
+	e.state.SkippedRuns = append(e.state.SkippedRuns, run)
+	if len(e.state.SkippedRuns) > maxSkippedRuns {
+		e.state.SkippedRuns = e.state.SkippedRuns[len(e.state.SkippedRuns)-maxSkippedRuns:]
+	}
+}
+
+func (s *Scheduler) fire(e *entry, due time.Time) {
+	logger.Info("Running schedule", e.Name)
+	run := Run{Time: due, Status: RunSucceeded}
+	if err := s.execute(e.Schedule); err != nil {
+		logger.Error("Schedule failed", e.Name, err)
+		run.Status = RunFailed
+		run.Error = err.Error()
+	}
+
+	s.mu.Lock()
+	e.state.LastRun = &run
+	s.save()
+	status := s.status(e)
+	s.mu.Unlock()
+
+	s.notify(status)
+}
+
+func (s *Scheduler) execute(schedule config.Schedule) error {
+	if schedule.Scene != "" {
+		report, err := s.scenes.Activate(schedule.Scene)
+		if err != nil {
+			return err
+		}
+		if !report.Success {
+			return fmt.Errorf("scene '%s' failed", schedule.Scene)
+		}
+		return nil
+	}
+
+	command, err := schedule.Validate()
+	if err != nil {
+		return err
+	}
+	command.Source = commands.SourceSchedule
+
+	if schedule.Group != "" {
+		group := s.registry.GetGroup(schedule.Group)
+		if group == nil {
+			return fmt.Errorf("group '%s' not found", schedule.Group)
+		}
+		if err := group.Apply(command); err != nil {
+			return fmt.Errorf("group '%s' failed: %w", schedule.Group, err)
+		}
+		return nil
+	}
+
+	actor := s.registry.GetActor(schedule.Actor)
+	if actor == nil {
+		return fmt.Errorf("actor '%s' not found", schedule.Actor)
+	}
+	return actor.Apply(command)
+}
+
+func (s *Scheduler) notify(statuses ...Status) {
+	if s.OnChange == nil {
+		return
+	}
+	for _, status := range statuses {
+		s.OnChange(status)
+	}
+}
+
+// save must be called with the lock held
+func (s *Scheduler) save() {
+	stored := map[string]*state{}
+	for _, e := range s.entries {
+		stored[e.Name] = e.state
+	}
+	if err := storage.WriteJSON(s.file, stored); err != nil {
+		logger.Error("Failed to save schedules", err)
+	}
+}
+
+// status must be called with the lock held
+func (s *Scheduler) status(e *entry) Status {
+	status := Status{
+		Schedule:    e.Schedule,
+		Enabled:     e.enabled(),
+		NextRuns:    []time.Time{},
+		SkippedRuns: append([]Run{}, e.state.SkippedRuns...),
+	}
+	if e.state.LastRun != nil {
+		run := *e.state.LastRun
+		status.LastRun = &run
+	}
+
+	next := s.now()
+	for len(status.NextRuns) < previewRuns {
+		next = e.trigger.Next(next)
+		if next.IsZero() {
+			break
+		}
+		status.NextRuns = append(status.NextRuns, next)
+	}
+	return status
+}
+
+func (s *Scheduler) List() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := []Status{}
+	for _, e := range s.entries {
+		result = append(result, s.status(e))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+func (s *Scheduler) Get(name string) (Status, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[strings.ToLower(name)]
+	if !ok {
+		return Status{}, false
+	}
+	return s.status(e), true
+}
+
+// SetEnabled enables or disables a schedule. The setting is persisted and
+// takes precedence over the configuration.
+func (s *Scheduler) SetEnabled(name string, enabled bool) error {
+	s.mu.Lock()
+	e, ok := s.entries[strings.ToLower(name)]
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("schedule '%s' not found", name)
+	}
+
+	e.state.Enabled = &enabled
+	s.save()
+	status := s.status(e)
+	s.mu.Unlock()
+
+	logger.Info("Schedule", e.Name, "enabled:", enabled)
+	s.notify(status)
+	return nil
+}
@@ -0,0 +1,97 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/commands"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/config"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/eltako"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/solar"
+)
+
+func newTestScheduler(t *testing.T, dataDir string, now time.Time) *Scheduler {
+	t.Helper()
+
+	cfg := config.Config{
+		DataDir: dataDir,
+		Schedules: []config.Schedule{
+			{
+				Name:   "morning",
+				Cron:   "0 7 * * *",
+				Actor:  "living room",
+				Action: commands.Action{Action: commands.ActionOpen},
+			},
+		},
+	}
+
+	s, err := New(cfg, eltako.NewActorRegistry(), nil)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func TestDisabledRunsAreSkipped(t *testing.T) {
+	dir := t.TempDir()
+	s := newTestScheduler(t, dir, time.Date(2025, 1, 1, 6, 0, 0, 0, time.UTC))
+	if err := s.SetEnabled("Morning", false); err != nil {
+		t.Fatalf("SetEnabled failed: %v", err)
+	}
+
+	s.mu.Lock()
+	s.entries["morning"].next = time.Date(2025, 1, 1, 7, 0, 0, 0, time.UTC)
+	s.mu.Unlock()
+	s.tick(time.Date(2025, 1, 1, 7, 0, 0, 0, time.UTC))
+
+	status, _ := newTestScheduler(t, dir, time.Date(2025, 1, 1, 7, 0, 0, 0, time.UTC)).Get("morning")
+	if status.Enabled {
+		t.Error("expected the schedule to stay disabled after a restart")
+	}
+	if len(status.SkippedRuns) != 1 || status.SkippedRuns[0].Reason != ReasonDisabled {
+		t.Errorf("unexpected skipped runs %+v", status.SkippedRuns)
+	}
+	if len(status.NextRuns) != previewRuns || !status.NextRuns[0].Equal(time.Date(2025, 1, 2, 7, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected next runs %+v", status.NextRuns)
+	}
+}
+
+func TestMissedRunsArePersisted(t *testing.T) {
+	dir := t.TempDir()
+
+	// The gateway was stopped after the run on Dec 31 and is started three days later
+	s := newTestScheduler(t, dir, time.Date(2025, 1, 4, 6, 0, 0, 0, time.UTC))
+	s.entries["morning"].state.LastDue = time.Date(2024, 12, 31, 7, 0, 0, 0, time.UTC)
+	s.Start()
+
+	status, _ := newTestScheduler(t, dir, time.Date(2025, 1, 4, 6, 0, 0, 0, time.UTC)).Get("morning")
+	if len(status.SkippedRuns) != 3 {
+		t.Fatalf("expected 3 skipped runs, got %+v", status.SkippedRuns)
+	}
+	for _, run := range status.SkippedRuns {
+		if run.Reason != ReasonMissed {
+			t.Errorf("unexpected skipped run %+v", run)
+		}
+	}
+}
+
+func TestSunTriggerWithOffset(t *testing.T) {
+	location := solar.Location{Latitude: 52.52, Longitude: 13.405}
+	trigger, err := NewSunTrigger(location, SunSet, -30*time.Minute)
+	if err != nil {
+		t.Fatalf("NewSunTrigger failed: %v", err)
+	}
+
+	after := time.Date(2025, 6, 21, 12, 0, 0, 0, time.UTC)
+	sunset, _ := location.Sunset(after)
+	if next := trigger.Next(after); !next.Equal(sunset.Add(-30 * time.Minute)) {
+		t.Errorf("expected %v, got %v", sunset.Add(-30*time.Minute), next)
+	}
+
+	// After today's run the next one is tomorrow
+	tomorrow, _ := location.Sunset(after.AddDate(0, 0, 1))
+	if next := trigger.Next(sunset); !next.Equal(tomorrow.Add(-30 * time.Minute)) {
+		t.Errorf("expected %v, got %v", tomorrow.Add(-30*time.Minute), next)
+	}
+}
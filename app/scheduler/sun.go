@@ -0,0 +1,47 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/solar"
+)
+
+const (
+	SunRise = "sunrise"
+	SunSet  = "sunset"
+)
+
+// SunTrigger is due at sunrise or sunset shifted by an offset
+type SunTrigger struct {
+	location solar.Location
+	event    string
+	offset   time.Duration
+}
+
+func NewSunTrigger(location solar.Location, event string, offset time.Duration) (*SunTrigger, error) {
+	if event != SunRise && event != SunSet {
+		return nil, fmt.Errorf("invalid sun event '%s', expected %s or %s", event, SunRise, SunSet)
+	}
+	return &SunTrigger{location: location, event: event, offset: offset}, nil
+}
+
+func (t *SunTrigger) Next(after time.Time) time.Time {
+	// Start a day earlier, a large offset may move the event to the next day
+	day := time.Date(after.Year(), after.Month(), after.Day()-1, 12, 0, 0, 0, after.Location())
+
+	for i := 0; i < 400; i++ {
+		var event time.Time
+		var ok bool
+		if t.event == SunRise {
+			event, ok = t.location.Sunrise(day.AddDate(0, 0, i))
+		} else {
+			event, ok = t.location.Sunset(day.AddDate(0, 0, i))
+		}
+
+		if ok && event.Add(t.offset).After(after) {
+			return event.Add(t.offset)
+		}
+	}
+	return time.Time{}
+}
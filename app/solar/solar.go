@@ -0,0 +1,114 @@
+// Package solar calculates the position of the sun and the times of
+// sunrise and sunset. The accuracy (about one minute) is sufficient to
+// control shadings and no network access is needed.
+package solar
+
+import (
+	"math"
+	"time"
+)
+
+// The elevation of the upper limb of the sun at sunrise and sunset,
+// including atmospheric refraction
+const horizon = -0.833
+
+type Location struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Position returns the azimuth (degrees clockwise from north) and the
+// elevation (degrees above the horizon) of the sun
+func (l Location) Position(t time.Time) (azimuth, elevation float64) {
+	// Days since J2000.0
+	n := float64(t.UnixNano())/float64(24*time.Hour) + 2440587.5 - 2451545.0
+
+	meanLongitude := normalize(280.460 + 0.9856474*n)
+	meanAnomaly := radians(normalize(357.528 + 0.9856003*n))
+	eclipticLongitude := radians(meanLongitude + 1.915*math.Sin(meanAnomaly) + 0.020*math.Sin(2*meanAnomaly))
+	obliquity := radians(23.439 - 0.0000004*n)
+
+	rightAscension := math.Atan2(math.Cos(obliquity)*math.Sin(eclipticLongitude), math.Cos(eclipticLongitude))
+	declination := math.Asin(math.Sin(obliquity) * math.Sin(eclipticLongitude))
+
+	siderealTime := normalize(280.46061837 + 360.98564736629*n + l.Longitude)
+	hourAngle := radians(siderealTime) - rightAscension
+	latitude := radians(l.Latitude)
+
+	elevation = math.Asin(math.Sin(latitude)*math.Sin(declination) +
+		math.Cos(latitude)*math.Cos(declination)*math.Cos(hourAngle))
+	azimuth = math.Atan2(math.Sin(hourAngle),
+		math.Cos(hourAngle)*math.Sin(latitude)-math.Tan(declination)*math.Cos(latitude))
+
+	return normalize(degrees(azimuth) + 180), degrees(elevation)
+}
+
+// Sunrise returns the time of sunrise on the day of t in the location of
+// t. The second result is false if the sun does not rise on that day.
+func (l Location) Sunrise(t time.Time) (time.Time, bool) {
+	return l.crossing(t, true)
+}
+
+// Sunset returns the time of sunset on the day of t in the location of
+// t. The second result is false if the sun does not set on that day.
+func (l Location) Sunset(t time.Time) (time.Time, bool) {
+	return l.crossing(t, false)
+}
+
+func (l Location) crossing(t time.Time, rising bool) (time.Time, bool) {
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	end := time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+
+	const step = 10 * time.Minute
+	for from := start; from.Before(end); from = from.Add(step) {
+		to := from.Add(step)
+		if to.After(end) {
+			to = end
+		}
+
+		_, e1 := l.Position(from)
+		_, e2 := l.Position(to)
+		if rising && e1 < horizon && e2 >= horizon || !rising && e1 >= horizon && e2 < horizon {
+			return l.bisect(from, to, rising), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (l Location) bisect(from, to time.Time, rising bool) time.Time {
+	for to.Sub(from) > time.Second {
+		mid := from.Add(to.Sub(from) / 2)
+		_, elevation := l.Position(mid)
+		if (elevation < horizon) == rising {
+			from = mid
+		} else {
+			to = mid
+		}
+	}
+	return to.Truncate(time.Second)
+}
+
+// AngleDiff returns the absolute difference between two directions in degrees
+func AngleDiff(a, b float64) float64 {
+	diff := math.Abs(normalize(a - b))
+	if diff > 180 {
+		return 360 - diff
+	}
+	return diff
+}
+
+func normalize(deg float64) float64 {
+	deg = math.Mod(deg, 360)
+	if deg < 0 {
+		deg += 360
+	}
+	return deg
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
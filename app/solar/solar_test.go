@@ -0,0 +1,66 @@
+package solar
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var berlin = Location{Latitude: 52.52, Longitude: 13.405}
+
+func assertNear(t *testing.T, name string, expected, actual time.Time) {
+	t.Helper()
+	if diff := actual.Sub(expected); diff < -3*time.Minute || diff > 3*time.Minute {
+		t.Errorf("%s: expected about %v, got %v", name, expected, actual)
+	}
+}
+
+func TestSunriseAndSunset(t *testing.T) {
+	cest := time.FixedZone("CEST", 2*60*60)
+	day := time.Date(2025, 6, 21, 12, 0, 0, 0, cest)
+
+	sunrise, ok := berlin.Sunrise(day)
+	if !ok {
+		t.Fatal("expected a sunrise")
+	}
+	assertNear(t, "sunrise", time.Date(2025, 6, 21, 4, 43, 0, 0, cest), sunrise)
+
+	sunset, ok := berlin.Sunset(day)
+	if !ok {
+		t.Fatal("expected a sunset")
+	}
+	assertNear(t, "sunset", time.Date(2025, 6, 21, 21, 33, 0, 0, cest), sunset)
+}
+
+func TestPolarNight(t *testing.T) {
+	tromso := Location{Latitude: 69.65, Longitude: 18.96}
+	if _, ok := tromso.Sunrise(time.Date(2025, 12, 21, 12, 0, 0, 0, time.UTC)); ok {
+		t.Error("expected no sunrise during polar night")
+	}
+}
+
+func TestPosition(t *testing.T) {
+	// Solar noon in Berlin at the summer solstice
+	azimuth, elevation := berlin.Position(time.Date(2025, 6, 21, 11, 9, 0, 0, time.UTC))
+	if math.Abs(azimuth-180) > 2 {
+		t.Errorf("expected azimuth near 180, got %f", azimuth)
+	}
+	if math.Abs(elevation-60.9) > 0.5 {
+		t.Errorf("expected elevation near 60.9, got %f", elevation)
+	}
+
+	// Morning sun in the east
+	azimuth, _ = berlin.Position(time.Date(2025, 3, 20, 6, 0, 0, 0, time.UTC))
+	if math.Abs(azimuth-90) > 10 {
+		t.Errorf("expected azimuth near 90, got %f", azimuth)
+	}
+}
+
+func TestAngleDiff(t *testing.T) {
+	if diff := AngleDiff(350, 10); diff != 20 {
+		t.Errorf("expected 20, got %f", diff)
+	}
+	if diff := AngleDiff(90, 270); diff != 180 {
+		t.Errorf("expected 180, got %f", diff)
+	}
+}
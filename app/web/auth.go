@@ -0,0 +1,259 @@
+package web
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/config"
+	"github.com/philipparndt/go-logger"
+)
+
+const sessionCookie = "eltako_session"
+
+// failedLoginDelay slows down guessing passwords
+const failedLoginDelay = time.Second
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+func (p Principal) CanControl() bool {
+	return p.Role == config.RoleControl
+}
+
+type session struct {
+	principal Principal
+	expires   time.Time
+}
+
+// Authenticator checks bearer tokens and session cookies. Requests are not
+// authenticated when it is nil.
+type Authenticator struct {
+	cfg      config.AuthConfig
+	maxAge   time.Duration
+	sessions map[string]*session
+	mu       sync.Mutex
+}
+
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// AuthStatus tells the web interface whether a login is required
+type AuthStatus struct {
+	Enabled       bool       `json:"enabled"`
+	Authenticated bool       `json:"authenticated"`
+	Principal     *Principal `json:"principal,omitempty"`
+}
+
+func NewAuthenticator(cfg *config.AuthConfig) *Authenticator {
+	if cfg == nil || (len(cfg.Tokens) == 0 && len(cfg.Users) == 0) {
+		return nil
+	}
+	return &Authenticator{
+		cfg:      *cfg,
+		maxAge:   time.Duration(cfg.SessionMaxAge) * time.Millisecond,
+		sessions: make(map[string]*session),
+	}
+}
+
+func equal(a string, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// checkPassword compares the password with a plain text or "sha256:" hashed one
+func checkPassword(configured string, password string) bool {
+	if hash, ok := strings.CutPrefix(configured, "sha256:"); ok {
+		sum := sha256.Sum256([]byte(password))
+		return equal(strings.ToLower(hash), hex.EncodeToString(sum[:]))
+	}
+	return equal(configured, password)
+}
+
+func (a *Authenticator) tokenPrincipal(token string) (Principal, bool) {
+	for _, t := range a.cfg.Tokens {
+		if equal(t.Token, token) {
+			return Principal{Name: t.Name, Role: t.Role}, true
+		}
+	}
+	return Principal{}, false
+}
+
+func (a *Authenticator) sessionPrincipal(id string) (Principal, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, ok := a.sessions[id]
+	if !ok {
+		return Principal{}, false
+	}
+	if time.Now().After(s.expires) {
+		delete(a.sessions, id)
+		return Principal{}, false
+	}
+	return s.principal, true
+}
+
+// authenticate returns the principal of the bearer token or session cookie
+func (a *Authenticator) authenticate(r *http.Request) (Principal, bool) {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return a.tokenPrincipal(token)
+	}
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		return a.sessionPrincipal(cookie.Value)
+	}
+	return Principal{}, false
+}
+
+// isPublic tells whether the path is served without authentication. The
+// static files of the web interface are public, so the login can be shown.
+func isPublic(path string) bool {
+	switch {
+	case path == "/healthz", path == "/readyz":
+		return true
+	case strings.HasPrefix(path, "/api/auth/"):
+		return true
+	case strings.HasPrefix(path, "/api/"), path == "/api", path == "/events", path == "/metrics":
+		return false
+	}
+	return true
+}
+
+func isReadOnly(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// Middleware rejects unauthenticated requests with 401 and requests of
+// read-only principals that change something with 403
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isPublic(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal, ok := a.authenticate(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="eltako"`)
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+		if !isReadOnly(r.Method) && !principal.CanControl() {
+			http.Error(w, "Role does not allow to control the actors", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func newSessionID() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (a *Authenticator) login(w http.ResponseWriter, r *http.Request) {
+	var request LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var principal *Principal
+	for _, user := range a.cfg.Users {
+		if equal(user.Username, request.Username) && checkPassword(user.Password, request.Password) {
+			principal = &Principal{Name: user.Username, Role: user.Role}
+			break
+		}
+	}
+	if principal == nil {
+		logger.Warn("Failed login", request.Username, r.RemoteAddr)
+		time.Sleep(failedLoginDelay)
+		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		return
+	}
+
+	id := newSessionID()
+	expires := time.Now().Add(a.maxAge)
+	a.mu.Lock()
+	for key, s := range a.sessions {
+		if time.Now().After(s.expires) {
+			delete(a.sessions, key)
+		}
+	}
+	a.sessions[id] = &session{principal: *principal, expires: expires}
+	a.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    id,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	logger.Info("User logged in", principal.Name)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AuthStatus{Enabled: true, Authenticated: true, Principal: principal})
+}
+
+func (a *Authenticator) logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		a.mu.Lock()
+		delete(a.sessions, cookie.Value)
+		a.mu.Unlock()
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (ws *WebServer) getAuthStatus(w http.ResponseWriter, r *http.Request) {
+	status := AuthStatus{Enabled: ws.auth != nil, Authenticated: ws.auth == nil}
+	if ws.auth != nil {
+		if principal, ok := ws.auth.authenticate(r); ok {
+			status.Authenticated = true
+			status.Principal = &principal
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+func (ws *WebServer) login(w http.ResponseWriter, r *http.Request) {
+	if ws.auth == nil {
+		http.Error(w, "Authentication is not enabled", http.StatusNotFound)
+		return
+	}
+	ws.auth.login(w, r)
+}
+
+func (ws *WebServer) logout(w http.ResponseWriter, r *http.Request) {
+	if ws.auth == nil {
+		http.Error(w, "Authentication is not enabled", http.StatusNotFound)
+		return
+	}
+	ws.auth.logout(w, r)
+}
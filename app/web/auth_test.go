@@ -0,0 +1,94 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/config"
+)
+
+func newTestAuthenticator() *Authenticator {
+	return NewAuthenticator(&config.AuthConfig{
+		Tokens: []config.APIToken{
+			{Name: "dashboard", Token: "read-token", Role: config.RoleRead},
+			{Name: "automation", Token: "control-token", Role: config.RoleControl},
+		},
+		Users: []config.User{
+			// sha256 of "secret"
+			{Username: "admin", Password: "sha256:2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b", Role: config.RoleControl},
+		},
+		SessionMaxAge: 60000,
+	})
+}
+
+func serve(a *Authenticator, r *http.Request) int {
+	recorder := httptest.NewRecorder()
+	a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(recorder, r)
+	return recorder.Code
+}
+
+func TestMiddlewareRoles(t *testing.T) {
+	a := newTestAuthenticator()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		code   int
+	}{
+		{"static files are public", "GET", "/index.html", "", http.StatusOK},
+		{"readiness is public", "GET", "/readyz", "", http.StatusOK},
+		{"api requires authentication", "GET", "/api/actors", "", http.StatusUnauthorized},
+		{"events require authentication", "GET", "/events", "", http.StatusUnauthorized},
+		{"unknown token", "GET", "/api/actors", "wrong", http.StatusUnauthorized},
+		{"read token reads", "GET", "/metrics", "read-token", http.StatusOK},
+		{"read token cannot control", "POST", "/api/actors/living/stop", "read-token", http.StatusForbidden},
+		{"control token controls", "POST", "/api/actors/living/stop", "control-token", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			if code := serve(a, r); code != tt.code {
+				t.Errorf("expected %d, got %d", tt.code, code)
+			}
+		})
+	}
+}
+
+func TestLoginSession(t *testing.T) {
+	a := newTestAuthenticator()
+
+	recorder := httptest.NewRecorder()
+	a.login(recorder, httptest.NewRequest("POST", "/api/auth/login", strings.NewReader(`{"username":"admin","password":"secret"}`)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected login to succeed, got %d", recorder.Code)
+	}
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != sessionCookie || !cookies[0].HttpOnly {
+		t.Fatalf("expected an HttpOnly session cookie, got %+v", cookies)
+	}
+
+	r := httptest.NewRequest("POST", "/api/actors/living/stop", nil)
+	r.AddCookie(cookies[0])
+	if code := serve(a, r); code != http.StatusOK {
+		t.Errorf("expected session to allow control, got %d", code)
+	}
+
+	a.logout(httptest.NewRecorder(), r)
+	if code := serve(a, r); code != http.StatusUnauthorized {
+		t.Errorf("expected session to end with the logout, got %d", code)
+	}
+}
+
+func TestNewAuthenticatorDisabledWithoutCredentials(t *testing.T) {
+	if NewAuthenticator(nil) != nil || NewAuthenticator(&config.AuthConfig{}) != nil {
+		t.Error("expected authentication to be disabled without tokens and users")
+	}
+}
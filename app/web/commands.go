@@ -0,0 +1,134 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/commands"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/eltako"
+	"github.com/philipparndt/go-logger"
+)
+
+const (
+	defaultWaitTimeout = 60 * time.Second
+	maxWaitTimeout     = 300 * time.Second
+)
+
+type CommandAccepted struct {
+	Status    string `json:"status"`
+	CommandID string `json:"commandId"`
+}
+
+type CommandResult struct {
+	CommandID string `json:"commandId"`
+	eltako.Result
+}
+
+// waitOptions reads ?wait=true and the optional ?timeout=<seconds>
+func waitOptions(r *http.Request) (bool, time.Duration, error) {
+	query := r.URL.Query()
+	if query.Get("wait") == "" {
+		return false, 0, nil
+	}
+
+	wait, err := strconv.ParseBool(query.Get("wait"))
+	if err != nil {
+		return false, 0, fmt.Errorf("invalid wait parameter")
+	}
+
+	timeout := defaultWaitTimeout
+	if value := query.Get("timeout"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds <= 0 || time.Duration(seconds)*time.Second > maxWaitTimeout {
+			return false, 0, fmt.Errorf("timeout must be between 1 and %d seconds", int(maxWaitTimeout.Seconds()))
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+	return wait, timeout, nil
+}
+
+func statusCodeOf(outcome eltako.Outcome) int {
+	switch outcome {
+	case eltako.OutcomeAccepted, eltako.OutcomeReached:
+		return http.StatusOK
+	case eltako.OutcomeRefused, eltako.OutcomeStopped, eltako.OutcomeCancelled, eltako.OutcomeSuperseded:
+		return http.StatusConflict
+	case eltako.OutcomeTimeout:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+func writeResult(w http.ResponseWriter, command commands.LLCommand, result eltako.Result) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCodeOf(result.Outcome))
+	json.NewEncoder(w).Encode(CommandResult{CommandID: command.ID, Result: result})
+}
+
+// dispatch runs the command in the background and answers with 202 and the
+// command ID. With ?wait=true it waits for the outcome instead.
+func (ws *WebServer) dispatch(w http.ResponseWriter, r *http.Request, actor *eltako.ShadingActor, command commands.LLCommand) {
+	wait, timeout, err := waitOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Broadcast state change after a brief delay to allow the actor to update
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		ws.broadcastStateChange()
+	}()
+
+	if !wait {
+		if _, err := actor.Submit(command); err != nil {
+			logger.Warn("Command refused", command.ID, err)
+			writeResult(w, command, actor.ResultOf(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(CommandAccepted{Status: "accepted", CommandID: command.ID})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	result := actor.Execute(ctx, command)
+	logger.Info("Command finished", command.ID, actor.Name, result.Outcome)
+	writeResult(w, command, result)
+}
+
+func (ws *WebServer) getCommand(w http.ResponseWriter, r *http.Request) {
+	commandID := chi.URLParam(r, "commandId")
+	record, ok := eltako.Commands.Get(commandID)
+
+	if !ok {
+		http.Error(w, fmt.Sprintf("Command '%s' not found", commandID), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(record)
+}
+
+func (ws *WebServer) getActorCommands(w http.ResponseWriter, r *http.Request) {
+	actorName := chi.URLParam(r, "actorName")
+	actor := ws.registry.GetActor(actorName)
+
+	if actor == nil {
+		http.Error(w, fmt.Sprintf("Actor '%s' not found", actorName), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(eltako.Commands.ForActor(actor.Name))
+}
@@ -0,0 +1,31 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/mqtt-home/eltako-to-mqtt-gw/health"
+)
+
+type Liveness struct {
+	Status    health.Status `json:"status"`
+	StartedAt time.Time     `json:"startedAt"`
+}
+
+// getHealthz answers as long as the process is able to serve requests
+func (ws *WebServer) getHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Liveness{Status: health.StatusUp, StartedAt: health.StartedAt})
+}
+
+// getReadyz answers with 503 while a subsystem is not ready
+func (ws *WebServer) getReadyz(w http.ResponseWriter, r *http.Request) {
+	report := health.Ready()
+
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status != health.StatusUp {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
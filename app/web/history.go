@@ -0,0 +1,57 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/eltako"
+)
+
+// defaultHistoryRange is used when the request does not specify ?from=
+const defaultHistoryRange = 24 * time.Hour
+
+// parseTime reads an RFC 3339 timestamp from the query, returning the
+// fallback when the parameter is missing
+func parseTime(r *http.Request, name string, fallback time.Time) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s parameter, expected an RFC 3339 timestamp", name)
+	}
+	return t, nil
+}
+
+func (ws *WebServer) getActorHistory(w http.ResponseWriter, r *http.Request) {
+	actorName := chi.URLParam(r, "actorName")
+	actor := ws.registry.GetActor(actorName)
+
+	if actor == nil {
+		http.Error(w, fmt.Sprintf("Actor '%s' not found", actorName), http.StatusNotFound)
+		return
+	}
+
+	to, err := parseTime(r, "to", time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	from, err := parseTime(r, "from", to.Add(-defaultHistoryRange))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if from.After(to) {
+		http.Error(w, "from must not be after to", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(eltako.History.Range(actor.Name, from, to))
+}
@@ -0,0 +1,107 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/scenes"
+	"github.com/philipparndt/go-logger"
+)
+
+type SceneStatus struct {
+	scenes.Scene
+	LastReport *scenes.Report `json:"lastReport,omitempty"`
+}
+
+func (ws *WebServer) getAllScenes(w http.ResponseWriter, r *http.Request) {
+	result := []SceneStatus{}
+	for _, scene := range ws.scenes.List() {
+		result = append(result, SceneStatus{
+			Scene:      scene,
+			LastReport: ws.scenes.LastReport(scene.Name),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (ws *WebServer) getScene(w http.ResponseWriter, r *http.Request) {
+	sceneName := chi.URLParam(r, "sceneName")
+	scene, ok := ws.scenes.Get(sceneName)
+
+	if !ok {
+		http.Error(w, fmt.Sprintf("Scene '%s' not found", sceneName), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SceneStatus{
+		Scene:      scene,
+		LastReport: ws.scenes.LastReport(scene.Name),
+	})
+}
+
+func (ws *WebServer) putScene(w http.ResponseWriter, r *http.Request) {
+	sceneName := chi.URLParam(r, "sceneName")
+
+	var scene scenes.Scene
+	if err := json.NewDecoder(r.Body).Decode(&scene); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	scene.Name = sceneName
+
+	if err := ws.scenes.Put(scene); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Info(fmt.Sprintf("Saved scene %s", sceneName))
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+func (ws *WebServer) deleteScene(w http.ResponseWriter, r *http.Request) {
+	sceneName := chi.URLParam(r, "sceneName")
+
+	if _, ok := ws.scenes.Get(sceneName); !ok {
+		http.Error(w, fmt.Sprintf("Scene '%s' not found", sceneName), http.StatusNotFound)
+		return
+	}
+
+	if err := ws.scenes.Delete(sceneName); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Info(fmt.Sprintf("Deleted scene %s", sceneName))
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+func (ws *WebServer) activateScene(w http.ResponseWriter, r *http.Request) {
+	sceneName := chi.URLParam(r, "sceneName")
+
+	if _, ok := ws.scenes.Get(sceneName); !ok {
+		http.Error(w, fmt.Sprintf("Scene '%s' not found", sceneName), http.StatusNotFound)
+		return
+	}
+
+	// The report is published over MQTT and available via GET /api/scenes/{name}
+	go func() {
+		_, err := ws.scenes.Activate(sceneName)
+		if err != nil {
+			logger.Error("Failed to activate scene", err)
+		}
+	}()
+
+	logger.Info(fmt.Sprintf("Activate scene %s", sceneName))
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "accepted"})
+}
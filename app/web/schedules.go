@@ -0,0 +1,59 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (ws *WebServer) getAllSchedules(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ws.schedules.List())
+}
+
+func (ws *WebServer) getSchedule(w http.ResponseWriter, r *http.Request) {
+	scheduleName := chi.URLParam(r, "scheduleName")
+	status, ok := ws.schedules.Get(scheduleName)
+
+	if !ok {
+		http.Error(w, fmt.Sprintf("Schedule '%s' not found", scheduleName), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+func (ws *WebServer) enableSchedule(w http.ResponseWriter, r *http.Request) {
+	ws.setScheduleEnabled(w, r, true)
+}
+
+func (ws *WebServer) disableSchedule(w http.ResponseWriter, r *http.Request) {
+	ws.setScheduleEnabled(w, r, false)
+}
+
+func (ws *WebServer) setScheduleEnabled(w http.ResponseWriter, r *http.Request, enabled bool) {
+	scheduleName := chi.URLParam(r, "scheduleName")
+
+	if err := ws.schedules.SetEnabled(scheduleName, enabled); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	status, _ := ws.schedules.Get(scheduleName)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+func (ws *WebServer) getSun(w http.ResponseWriter, r *http.Request) {
+	status, ok := ws.schedules.Sun()
+	if !ok {
+		http.Error(w, "No location configured", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
@@ -3,10 +3,11 @@ import { ActorStatus } from '@/types/actor';
 import { fetchActors, tiltAllActors } from '@/lib/api';
 import { useSSE } from '@/hooks/useSSE';
 import { ActorCard } from '@/components/ActorCard';
+import { ScenesCard } from '@/components/ScenesCard';
 import { ThemeToggle } from '@/components/ThemeToggle';
 import { Button } from '@/components/ui/button';
 import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card';
-import { RefreshCw, Home, Shield } from 'lucide-react';
+import { RefreshCw, Home, Shield, LogOut } from 'lucide-react';
 
 // Function to detect mobile devices
 const isMobileDevice = () => {
@@ -14,7 +15,14 @@ const isMobileDevice = () => {
          (window.innerWidth <= 768);
 };
 
-export function App() {
+interface AppProps {
+  // Read-only users can watch the actors but not control them
+  readOnly?: boolean;
+  user?: string;
+  onLogout?: () => void;
+}
+
+export function App({ readOnly = false, user, onLogout }: AppProps) {
   const [actors, setActors] = useState<ActorStatus[]>([]);
   const [isLoading, setIsLoading] = useState(true);
   const [error, setError] = useState<string | null>(null);
@@ -212,6 +220,17 @@ export function App() {
                 <Shield className="h-4 w-4" />
               </Button>
               <ThemeToggle />
+              {onLogout && (
+                <Button
+                  variant="ghost"
+                  size="icon"
+                  onClick={onLogout}
+                  className="h-9 w-9"
+                  title={user ? `Log out ${user}` : 'Log out'}
+                >
+                  <LogOut className="h-4 w-4" />
+                </Button>
+              )}
               {!isConnected && (
                 <Button
                   variant="outline"
@@ -227,7 +246,7 @@ export function App() {
             </div>
           </div>
 
-          {actors.length > 1 && (
+          {!readOnly && actors.length > 1 && (
             <Card className="mb-4 sm:mb-6">
               <CardHeader>
                 <CardTitle className="flex items-center justify-between">
@@ -279,6 +298,8 @@ export function App() {
               </CardContent>
             </Card>
           )}
+
+          {!readOnly && actors.length > 0 && <ScenesCard actors={actors} />}
         </div>
 
         {actors.length === 0 ? (
@@ -296,6 +317,7 @@ export function App() {
               <ActorCard
                 key={actor.name}
                 actor={actor}
+                readOnly={readOnly}
               />
             ))}
           </div>
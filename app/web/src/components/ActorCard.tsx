@@ -1,14 +1,16 @@
 import { useState, useEffect, useRef } from 'react';
-import { ActorStatus } from '@/types/actor';
-import { setActorPosition, tiltActor } from '@/lib/api';
+import { ActorHealth, ActorStatus } from '@/types/actor';
+import { CommandError, setActorPosition, tiltActor, stopActor } from '@/lib/api';
 import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card';
 import { Button } from '@/components/ui/button';
 import { Slider } from '@/components/ui/slider';
-import { ChevronUp, ChevronDown, RotateCcw, Lock } from 'lucide-react';
+import { HistoryChart } from '@/components/HistoryChart';
+import { ChevronUp, ChevronDown, RotateCcw, Lock, Square, History } from 'lucide-react';
 
 interface ActorCardProps {
     actor: ActorStatus;
     onRefresh?: () => void;
+    readOnly?: boolean;
 }
 
 // Function to detect mobile devices
@@ -17,14 +19,34 @@ const isMobileDevice = () => {
         (window.innerWidth <= 768);
 };
 
-export function ActorCard({ actor, onRefresh }: ActorCardProps) {
+const healthStyles: Record<ActorHealth, string> = {
+    pending: 'text-muted-foreground bg-muted',
+    online: 'text-green-700 bg-green-50 dark:bg-green-900/20',
+    degraded: 'text-yellow-700 bg-yellow-50 dark:bg-yellow-900/20',
+    reauthenticating: 'text-yellow-700 bg-yellow-50 dark:bg-yellow-900/20',
+    offline: 'text-red-700 bg-red-50 dark:bg-red-900/20',
+};
+
+const alertCommandError = (error: unknown, fallback: string) => {
+    // A movement stopped or replaced on purpose is not an error
+    if (error instanceof CommandError && ['stopped', 'cancelled', 'superseded'].includes(error.outcome)) {
+        return;
+    }
+    alert(error instanceof CommandError ? error.message : fallback);
+};
+
+export function ActorCard({ actor, onRefresh, readOnly = false }: ActorCardProps) {
     const [position, setPosition] = useState(actor.position);
     const [isLoading, setIsLoading] = useState(false);
     const [safeModeEnabled, setSafeModeEnabled] = useState(isMobileDevice());
     const [pendingAction, setPendingAction] = useState<string | null>(null);
     const [pendingTimeout, setPendingTimeout] = useState<NodeJS.Timeout | null>(null);
     const [isDragging, setIsDragging] = useState(false);
+    const [showHistory, setShowHistory] = useState(false);
     const executingActionRef = useRef(false);
+    // Pending actors have not answered yet and cannot execute commands
+    const isPending = actor.health === 'pending';
+    const canControl = !isPending && !readOnly;
 
     // Keep position in sync with actor prop only when not loading and not executing an action
     useEffect(() => {
@@ -70,7 +92,7 @@ export function ActorCard({ actor, onRefresh }: ActorCardProps) {
             // SSE will automatically update the UI, no need to manually refresh
         } catch (error) {
             console.error('Failed to set position:', error);
-            alert('Failed to set position. Please try again.');
+            alertCommandError(error, 'Failed to set position. Please try again.');
         } finally {
             setIsLoading(false);
             executingActionRef.current = false;
@@ -85,13 +107,24 @@ export function ActorCard({ actor, onRefresh }: ActorCardProps) {
             // SSE will automatically update the UI, no need to manually refresh
         } catch (error) {
             console.error('Failed to tilt:', error);
-            alert('Failed to tilt. Please try again.');
+            alertCommandError(error, 'Failed to tilt. Please try again.');
         } finally {
             setIsLoading(false);
             executingActionRef.current = false;
         }
     };
 
+    const handleStop = async () => {
+        // Stopping is always safe, so it bypasses the safe mode double tap
+        clearPendingAction();
+        try {
+            await stopActor(actor.name);
+        } catch (error) {
+            console.error('Failed to stop:', error);
+            alertCommandError(error, 'Failed to stop. Please try again.');
+        }
+    };
+
     const handleButtonAction = async (action: () => Promise<void>, actionName: string) => {
         if (safeModeEnabled) {
             if (pendingAction === actionName) {
@@ -160,12 +193,21 @@ export function ActorCard({ actor, onRefresh }: ActorCardProps) {
                         >
                             <Lock className="h-4 w-4" />
                         </Button>
+                        <Button
+                            variant={showHistory ? "default" : "ghost"}
+                            size="icon"
+                            onClick={() => setShowHistory(!showHistory)}
+                            className="h-8 w-8 shrink-0"
+                            title="Position history of the last 24 hours"
+                        >
+                            <History className="h-4 w-4" />
+                        </Button>
                         {onRefresh && (
                             <Button
                                 variant="ghost"
                                 size="icon"
                                 onClick={onRefresh}
-                                disabled={isLoading}
+                                disabled={isLoading || isPending}
                                 className="h-8 w-8 shrink-0"
                             >
                                 <RotateCcw className="h-4 w-4" />
@@ -175,6 +217,23 @@ export function ActorCard({ actor, onRefresh }: ActorCardProps) {
                 </CardTitle>
                 <CardDescription>
                     {actor.ip} {actor.serial && `(${actor.serial})`}
+                    {actor.health && actor.health !== 'online' && (
+                        <span className={`ml-2 text-xs px-2 py-0.5 rounded ${healthStyles[actor.health]}`}>
+                            {actor.health}
+                        </span>
+                    )}
+                    {actor.interlock?.contactOpen && (
+                        <span className="block text-xs text-yellow-700 mt-1">
+                            Contact open: {actor.interlock.minPosition !== undefined
+                                ? `closing below ${actor.interlock.minPosition}% refused`
+                                : 'commands refused'}
+                        </span>
+                    )}
+                    {actor.lock?.locked && (
+                        <span className="block text-xs text-red-700 mt-1">
+                            Locked: {actor.lock.reasons.join('; ')}
+                        </span>
+                    )}
                     {safeModeEnabled && (
                         <div className="text-xs text-blue-600 mt-1">
                             Safe Mode: Double tap buttons to execute
@@ -188,13 +247,20 @@ export function ActorCard({ actor, onRefresh }: ActorCardProps) {
                 </CardDescription>
             </CardHeader>
             <CardContent className="space-y-4">
+                {showHistory && <HistoryChart actorName={actor.name} position={actor.position} />}
+
                 <div className="space-y-2">
                     <div className="flex items-center justify-between text-sm">
             <span className={isDragging ? "text-blue-600 font-medium" : ""}>
               Position: {position}%
                 {isDragging && <span className="text-xs ml-1">(preview)</span>}
             </span>
-                        {actor.tilted && (
+                        {actor.moving ? (
+                            <span className="text-blue-600 font-medium">
+                {actor.direction === 'opening' ? 'Opening' : actor.direction === 'closing' ? 'Closing' : 'Moving'}
+                                {actor.targetPosition !== undefined && ` to ${actor.targetPosition}%`}
+              </span>
+                        ) : actor.tilted && (
                             <span className="text-blue-600 font-medium">
                 Tilted at {actor.tiltPosition}%
               </span>
@@ -208,27 +274,37 @@ export function ActorCard({ actor, onRefresh }: ActorCardProps) {
                             onValueCommit={handleSliderCommit}
                             max={100}
                             step={1}
-                            disabled={isLoading}
+                            disabled={isLoading || !canControl}
                         />
                     </div>
                 </div>
 
-                <div className="grid grid-cols-2 gap-3">
+                <div className="grid grid-cols-3 gap-3">
                     <Button
                         variant={pendingAction === 'close' ? "destructive" : "outline"}
                         size="sm"
                         onClick={() => handleButtonAction(() => handlePositionChange(0), 'close')}
-                        disabled={isLoading}
+                        disabled={isLoading || !canControl}
                         className="flex items-center gap-2 min-h-[44px] touch-manipulation"
                     >
                         <ChevronDown className="h-4 w-4" />
                         {pendingAction === 'close' ? 'Tap again' : 'Close'}
                     </Button>
+                    <Button
+                        variant="outline"
+                        size="sm"
+                        onClick={handleStop}
+                        disabled={!canControl}
+                        className="flex items-center gap-2 min-h-[44px] touch-manipulation"
+                    >
+                        <Square className="h-4 w-4" />
+                        Stop
+                    </Button>
                     <Button
                         variant={pendingAction === 'open' ? "destructive" : "outline"}
                         size="sm"
                         onClick={() => handleButtonAction(() => handlePositionChange(100), 'open')}
-                        disabled={isLoading}
+                        disabled={isLoading || !canControl}
                         className="flex items-center gap-2 min-h-[44px] touch-manipulation"
                     >
                         <ChevronUp className="h-4 w-4" />
@@ -243,7 +319,7 @@ export function ActorCard({ actor, onRefresh }: ActorCardProps) {
                             variant={pendingAction === 'tilt-closed' ? "destructive" : "secondary"}
                             size="sm"
                             onClick={() => handleButtonAction(() => handleTilt(0), 'tilt-closed')}
-                            disabled={isLoading}
+                            disabled={isLoading || !canControl}
                             className="min-h-[44px] touch-manipulation text-xs px-2"
                         >
                             {pendingAction === 'tilt-closed' ? 'Tap again' : 'Closed'}
@@ -252,7 +328,7 @@ export function ActorCard({ actor, onRefresh }: ActorCardProps) {
                             variant={pendingAction === 'tilt-half' ? "destructive" : "secondary"}
                             size="sm"
                             onClick={() => handleButtonAction(() => handleTilt(50), 'tilt-half')}
-                            disabled={isLoading}
+                            disabled={isLoading || !canControl}
                             className="min-h-[44px] touch-manipulation text-xs px-2"
                         >
                             {pendingAction === 'tilt-half' ? 'Tap again' : 'Half'}
@@ -261,7 +337,7 @@ export function ActorCard({ actor, onRefresh }: ActorCardProps) {
                             variant={pendingAction === 'tilt-open' ? "destructive" : "secondary"}
                             size="sm"
                             onClick={() => handleButtonAction(() => handleTilt(75), 'tilt-open')}
-                            disabled={isLoading}
+                            disabled={isLoading || !canControl}
                             className="min-h-[44px] touch-manipulation text-xs px-2"
                         >
                             {pendingAction === 'tilt-open' ? 'Tap again' : 'Open'}
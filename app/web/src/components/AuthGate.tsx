@@ -0,0 +1,98 @@
+import { FormEvent, useEffect, useState } from 'react';
+import { AuthStatus } from '@/types/auth';
+import { fetchAuthStatus, login, logout } from '@/lib/api';
+import { App } from '@/App';
+import { Button } from '@/components/ui/button';
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card';
+import { RefreshCw } from 'lucide-react';
+
+// AuthGate shows the login form while authentication is enabled and the user
+// has no session yet
+export function AuthGate() {
+    const [status, setStatus] = useState<AuthStatus | null>(null);
+    const [username, setUsername] = useState('');
+    const [password, setPassword] = useState('');
+    const [error, setError] = useState<string | null>(null);
+    const [isSubmitting, setIsSubmitting] = useState(false);
+
+    useEffect(() => {
+        fetchAuthStatus()
+            .then(setStatus)
+            .catch((err) => {
+                console.error('Failed to fetch authentication status:', err);
+                // Older servers have no authentication endpoint
+                setStatus({ enabled: false, authenticated: true });
+            });
+    }, []);
+
+    const handleLogin = async (event: FormEvent) => {
+        event.preventDefault();
+        setIsSubmitting(true);
+        try {
+            setStatus(await login(username, password));
+            setPassword('');
+            setError(null);
+        } catch (err) {
+            setError(err instanceof Error ? err.message : 'Login failed');
+        } finally {
+            setIsSubmitting(false);
+        }
+    };
+
+    const handleLogout = async () => {
+        await logout();
+        setStatus({ enabled: true, authenticated: false });
+    };
+
+    if (status === null) {
+        return (
+            <div className="min-h-screen bg-background flex items-center justify-center">
+                <RefreshCw className="h-8 w-8 animate-spin" />
+            </div>
+        );
+    }
+
+    if (status.authenticated) {
+        return (
+            <App
+                readOnly={status.principal?.role === 'read'}
+                user={status.principal?.name}
+                onLogout={status.enabled ? handleLogout : undefined}
+            />
+        );
+    }
+
+    return (
+        <div className="min-h-screen bg-background flex items-center justify-center p-4">
+            <Card className="w-full max-w-sm">
+                <CardHeader>
+                    <CardTitle>Eltako Control Panel</CardTitle>
+                    <CardDescription>Please log in to continue</CardDescription>
+                </CardHeader>
+                <CardContent>
+                    <form onSubmit={handleLogin} className="space-y-3">
+                        <input
+                            className="w-full border rounded px-2 py-2 text-sm bg-background"
+                            placeholder="Username"
+                            autoComplete="username"
+                            value={username}
+                            onChange={(e) => setUsername(e.target.value)}
+                        />
+                        <input
+                            className="w-full border rounded px-2 py-2 text-sm bg-background"
+                            placeholder="Password"
+                            type="password"
+                            autoComplete="current-password"
+                            value={password}
+                            onChange={(e) => setPassword(e.target.value)}
+                        />
+                        {error && <p className="text-xs text-red-600">{error}</p>}
+                        <Button type="submit" className="w-full" disabled={isSubmitting || !username || !password}>
+                            Log in
+                        </Button>
+                    </form>
+                </CardContent>
+            </Card>
+        </div>
+    );
+}
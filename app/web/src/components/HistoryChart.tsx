@@ -0,0 +1,101 @@
+import { useEffect, useState } from 'react';
+import { CommandSource } from '@/types/actor';
+import { HistoryEntry } from '@/types/history';
+import { fetchActorHistory } from '@/lib/api';
+
+interface HistoryChartProps {
+    actorName: string;
+    // Changes of the position trigger a reload
+    position: number;
+}
+
+const RANGE_MS = 24 * 60 * 60 * 1000;
+const WIDTH = 300;
+const HEIGHT = 100;
+
+const sourceColors: Record<CommandSource, string> = {
+    mqtt: '#2563eb',
+    rest: '#16a34a',
+    scene: '#9333ea',
+    schedule: '#ea580c',
+    protection: '#dc2626',
+    external: '#6b7280',
+};
+
+const formatTime = (time: Date) => time.toLocaleTimeString([], { hour: '2-digit', minute: '2-digit' });
+
+export function HistoryChart({ actorName, position }: HistoryChartProps) {
+    const [entries, setEntries] = useState<HistoryEntry[]>([]);
+    const [range, setRange] = useState(() => {
+        const to = new Date();
+        return { from: new Date(to.getTime() - RANGE_MS), to };
+    });
+    const [error, setError] = useState<string | null>(null);
+
+    useEffect(() => {
+        const to = new Date();
+        const from = new Date(to.getTime() - RANGE_MS);
+        fetchActorHistory(actorName, from, to)
+            .then((history) => {
+                setEntries(history);
+                setRange({ from, to });
+                setError(null);
+            })
+            .catch((err) => {
+                console.error('Failed to load history:', err);
+                setError('History not available');
+            });
+    }, [actorName, position]);
+
+    if (error) {
+        return <p className="text-xs text-muted-foreground">{error}</p>;
+    }
+
+    const span = range.to.getTime() - range.from.getTime();
+    const x = (time: string) => ((new Date(time).getTime() - range.from.getTime()) / span) * WIDTH;
+    // 100% (open) is drawn at the top
+    const y = (value: number) => HEIGHT - (value / 100) * HEIGHT;
+
+    const positions = entries.filter((entry) => entry.kind === 'position');
+    const commands = entries.filter((entry) => entry.kind === 'command');
+
+    // Step line: the position is kept until the next change
+    let path = '';
+    positions.forEach((entry, index) => {
+        const px = x(entry.time);
+        const py = y(entry.position);
+        path += index === 0 ? `M ${px} ${py}` : ` H ${px} V ${py}`;
+    });
+    if (positions.length > 0) {
+        path += ` H ${WIDTH}`;
+    }
+
+    return (
+        <div className="space-y-1">
+            <svg viewBox={`0 0 ${WIDTH} ${HEIGHT}`} className="w-full h-24 rounded bg-muted" preserveAspectRatio="none">
+                <path d={path} fill="none" stroke="currentColor" strokeWidth={1.5} vectorEffect="non-scaling-stroke" />
+                {commands.map((entry) => (
+                    <line
+                        key={entry.commandId}
+                        x1={x(entry.time)}
+                        x2={x(entry.time)}
+                        y1={0}
+                        y2={HEIGHT}
+                        stroke={sourceColors[entry.source ?? 'external']}
+                        strokeDasharray={entry.status === 'succeeded' ? undefined : '3 2'}
+                        vectorEffect="non-scaling-stroke"
+                    >
+                        <title>
+                            {`${formatTime(new Date(entry.time))} ${entry.action} ${entry.action === 'stop' ? '' : `${entry.position}% `}via ${entry.source}: ${entry.status}${entry.error ? ` (${entry.error})` : ''}`}
+                        </title>
+                    </line>
+                ))}
+            </svg>
+            <div className="flex justify-between text-xs text-muted-foreground">
+                <span>{formatTime(range.from)}</span>
+                <span>{positions.length === 0 && commands.length === 0 ? 'No changes recorded' : `${commands.length} commands`}</span>
+                <span>{formatTime(range.to)}</span>
+            </div>
+        </div>
+    );
+}
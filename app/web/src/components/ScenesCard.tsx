@@ -0,0 +1,246 @@
+import { useEffect, useState } from 'react';
+import { ActorStatus } from '@/types/actor';
+import { SceneAction, SceneActionType, SceneStatus } from '@/types/scene';
+import { activateScene, deleteScene, fetchScenes, saveScene } from '@/lib/api';
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card';
+import { Button } from '@/components/ui/button';
+import { Pencil, Play, Plus, Trash2, X } from 'lucide-react';
+
+interface ScenesCardProps {
+    actors: ActorStatus[];
+}
+
+const actionTypes: SceneActionType[] = ['open', 'close', 'set', 'tilt', 'closeandopenblinds', 'stop'];
+
+const needsPosition = (action: SceneActionType) => action === 'set' || action === 'tilt';
+
+interface EditorState {
+    name: string;
+    isNew: boolean;
+    actions: SceneAction[];
+}
+
+export function ScenesCard({ actors }: ScenesCardProps) {
+    const [scenes, setScenes] = useState<SceneStatus[]>([]);
+    const [editor, setEditor] = useState<EditorState | null>(null);
+    const [error, setError] = useState<string | null>(null);
+
+    const loadScenes = async () => {
+        try {
+            setScenes(await fetchScenes());
+        } catch (err) {
+            console.error('Failed to load scenes:', err);
+        }
+    };
+
+    useEffect(() => {
+        loadScenes();
+    }, []);
+
+    const handleActivate = async (name: string) => {
+        try {
+            await activateScene(name);
+            // The report is available once all actors are done
+            setTimeout(loadScenes, 2000);
+        } catch (err) {
+            console.error('Failed to activate scene:', err);
+            alert('Failed to activate scene. Please try again.');
+        }
+    };
+
+    const handleDelete = async (name: string) => {
+        if (!confirm(`Delete scene "${name}"?`)) {
+            return;
+        }
+        try {
+            await deleteScene(name);
+            await loadScenes();
+        } catch (err) {
+            console.error('Failed to delete scene:', err);
+            alert('Failed to delete scene. Please try again.');
+        }
+    };
+
+    const handleSave = async () => {
+        if (!editor) {
+            return;
+        }
+        if (editor.name.trim() === '') {
+            setError('Name must not be empty');
+            return;
+        }
+        try {
+            await saveScene(editor.name.trim(), editor.actions);
+            setEditor(null);
+            setError(null);
+            await loadScenes();
+        } catch (err) {
+            setError(err instanceof Error ? err.message : 'Failed to save scene');
+        }
+    };
+
+    const updateAction = (index: number, update: Partial<SceneAction>) => {
+        if (!editor) {
+            return;
+        }
+        const actions = editor.actions.map((action, i) => (i === index ? { ...action, ...update } : action));
+        setEditor({ ...editor, actions });
+    };
+
+    const addAction = () => {
+        if (!editor) {
+            return;
+        }
+        const actor = actors[0]?.name ?? '';
+        setEditor({ ...editor, actions: [...editor.actions, { actor, action: 'close', position: 0 }] });
+    };
+
+    const removeAction = (index: number) => {
+        if (!editor) {
+            return;
+        }
+        setEditor({ ...editor, actions: editor.actions.filter((_, i) => i !== index) });
+    };
+
+    return (
+        <Card className="mb-4 sm:mb-6">
+            <CardHeader>
+                <CardTitle className="flex items-center justify-between">
+                    <span>Scenes</span>
+                    {!editor && (
+                        <Button
+                            variant="ghost"
+                            size="icon"
+                            onClick={() => setEditor({ name: '', isNew: true, actions: [] })}
+                            className="h-8 w-8"
+                            title="New scene"
+                        >
+                            <Plus className="h-4 w-4" />
+                        </Button>
+                    )}
+                </CardTitle>
+                <CardDescription>Activate presets for several actors at once</CardDescription>
+            </CardHeader>
+            <CardContent className="space-y-3">
+                {editor ? (
+                    <div className="space-y-3">
+                        <input
+                            className="w-full rounded-md border border-input bg-background px-3 py-2 text-sm"
+                            placeholder="Scene name"
+                            value={editor.name}
+                            disabled={!editor.isNew}
+                            onChange={(e) => setEditor({ ...editor, name: e.target.value })}
+                        />
+                        {editor.actions.map((action, index) => (
+                            <div key={index} className="flex gap-2 items-center">
+                                <select
+                                    className="flex-1 min-w-0 rounded-md border border-input bg-background px-2 py-2 text-sm"
+                                    value={action.actor}
+                                    onChange={(e) => updateAction(index, { actor: e.target.value })}
+                                >
+                                    {actors.map((actor) => (
+                                        <option key={actor.name} value={actor.name}>
+                                            {actor.displayName}
+                                        </option>
+                                    ))}
+                                </select>
+                                <select
+                                    className="rounded-md border border-input bg-background px-2 py-2 text-sm"
+                                    value={action.action}
+                                    onChange={(e) => updateAction(index, { action: e.target.value as SceneActionType })}
+                                >
+                                    {actionTypes.map((type) => (
+                                        <option key={type} value={type}>
+                                            {type}
+                                        </option>
+                                    ))}
+                                </select>
+                                {needsPosition(action.action) && (
+                                    <input
+                                        type="number"
+                                        min={0}
+                                        max={100}
+                                        className="w-16 rounded-md border border-input bg-background px-2 py-2 text-sm"
+                                        value={action.position}
+                                        onChange={(e) => updateAction(index, { position: Number(e.target.value) })}
+                                    />
+                                )}
+                                <Button variant="ghost" size="icon" className="h-8 w-8 shrink-0" onClick={() => removeAction(index)}>
+                                    <X className="h-4 w-4" />
+                                </Button>
+                            </div>
+                        ))}
+                        {error && <p className="text-sm text-red-600">{error}</p>}
+                        <div className="flex gap-2">
+                            <Button variant="outline" size="sm" onClick={addAction} disabled={actors.length === 0}>
+                                <Plus className="h-4 w-4 mr-1" />
+                                Add actor
+                            </Button>
+                            <div className="flex-1" />
+                            <Button variant="ghost" size="sm" onClick={() => { setEditor(null); setError(null); }}>
+                                Cancel
+                            </Button>
+                            <Button size="sm" onClick={handleSave}>
+                                Save
+                            </Button>
+                        </div>
+                    </div>
+                ) : scenes.length === 0 ? (
+                    <p className="text-sm text-muted-foreground">No scenes defined</p>
+                ) : (
+                    scenes.map((scene) => (
+                        <div key={scene.name} className="flex items-center gap-2">
+                            <div className="flex-1 min-w-0">
+                                <p className="text-sm font-medium truncate">{scene.name}</p>
+                                <p className="text-xs text-muted-foreground">
+                                    {scene.actions.length} actor{scene.actions.length === 1 ? '' : 's'}
+                                    {scene.lastReport && (
+                                        <span className={scene.lastReport.success ? 'text-green-700 ml-2' : 'text-red-600 ml-2'}>
+                                            {scene.lastReport.success
+                                                ? 'Last run succeeded'
+                                                : `Last run failed: ${scene.lastReport.results
+                                                    .filter((result) => result.status !== 'ok')
+                                                    .map((result) => result.actor)
+                                                    .join(', ')}`}
+                                        </span>
+                                    )}
+                                </p>
+                            </div>
+                            {scene.source === 'api' && (
+                                <>
+                                    <Button
+                                        variant="ghost"
+                                        size="icon"
+                                        className="h-8 w-8 shrink-0"
+                                        title="Edit scene"
+                                        onClick={() => setEditor({ name: scene.name, isNew: false, actions: scene.actions })}
+                                    >
+                                        <Pencil className="h-4 w-4" />
+                                    </Button>
+                                    <Button
+                                        variant="ghost"
+                                        size="icon"
+                                        className="h-8 w-8 shrink-0"
+                                        title="Delete scene"
+                                        onClick={() => handleDelete(scene.name)}
+                                    >
+                                        <Trash2 className="h-4 w-4" />
+                                    </Button>
+                                </>
+                            )}
+                            <Button
+                                variant="secondary"
+                                size="sm"
+                                className="min-h-[44px] touch-manipulation shrink-0"
+                                onClick={() => handleActivate(scene.name)}
+                            >
+                                <Play className="h-4 w-4 mr-1" />
+                                Activate
+                            </Button>
+                        </div>
+                    ))
+                )}
+            </CardContent>
+        </Card>
+    );
+}
@@ -1,7 +1,58 @@
 import { ActorStatus } from '@/types/actor';
+import { AuthStatus } from '@/types/auth';
+import { HistoryEntry } from '@/types/history';
+import { SceneAction, SceneStatus } from '@/types/scene';
 
 const API_BASE = '/api';
 
+export type CommandOutcome = 'accepted' | 'reached' | 'stopped' | 'cancelled' | 'superseded' | 'timeout' | 'refused' | 'deviceError' | 'failed';
+
+// CommandError is thrown when a command did not reach its target, e.g.
+// because the actor is locked or the device rejected it
+export class CommandError extends Error {
+  constructor(message: string, public outcome: CommandOutcome) {
+    super(message);
+  }
+}
+
+async function commandError(response: Response, fallback: string): Promise<Error> {
+  try {
+    const body = await response.json();
+    if (body.outcome) {
+      return new CommandError(body.error ?? fallback, body.outcome);
+    }
+  } catch {
+    // Not a command result
+  }
+  return new Error(fallback);
+}
+
+export async function fetchAuthStatus(): Promise<AuthStatus> {
+  const response = await fetch(`${API_BASE}/auth/status`);
+  if (!response.ok) {
+    throw new Error('Failed to fetch authentication status');
+  }
+  return response.json();
+}
+
+export async function login(username: string, password: string): Promise<AuthStatus> {
+  const response = await fetch(`${API_BASE}/auth/login`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify({ username, password }),
+  });
+  if (!response.ok) {
+    throw new Error(response.status === 401 ? 'Invalid username or password' : 'Login failed');
+  }
+  return response.json();
+}
+
+export async function logout(): Promise<void> {
+  await fetch(`${API_BASE}/auth/logout`, { method: 'POST' });
+}
+
 export async function fetchActors(): Promise<ActorStatus[]> {
   const response = await fetch(`${API_BASE}/actors`);
   if (!response.ok) {
@@ -18,8 +69,18 @@ export async function fetchActor(name: string): Promise<ActorStatus> {
   return response.json();
 }
 
+export async function fetchActorHistory(name: string, from: Date, to: Date): Promise<HistoryEntry[]> {
+  const query = new URLSearchParams({ from: from.toISOString(), to: to.toISOString() });
+  const response = await fetch(`${API_BASE}/actors/${encodeURIComponent(name)}/history?${query}`);
+  if (!response.ok) {
+    throw new Error(`Failed to fetch history of actor ${name}`);
+  }
+  return response.json();
+}
+
+// Waits until the position is reached, so errors of the device are reported
 export async function setActorPosition(name: string, position: number): Promise<void> {
-  const response = await fetch(`${API_BASE}/actors/${encodeURIComponent(name)}/position`, {
+  const response = await fetch(`${API_BASE}/actors/${encodeURIComponent(name)}/position?wait=true`, {
     method: 'POST',
     headers: {
       'Content-Type': 'application/json',
@@ -27,12 +88,12 @@ export async function setActorPosition(name: string, position: number): Promise<
     body: JSON.stringify({ position }),
   });
   if (!response.ok) {
-    throw new Error(`Failed to set position for actor ${name}`);
+    throw await commandError(response, `Failed to set position for actor ${name}`);
   }
 }
 
 export async function tiltActor(name: string, position: number): Promise<void> {
-  const response = await fetch(`${API_BASE}/actors/${encodeURIComponent(name)}/tilt`, {
+  const response = await fetch(`${API_BASE}/actors/${encodeURIComponent(name)}/tilt?wait=true`, {
     method: 'POST',
     headers: {
       'Content-Type': 'application/json',
@@ -40,7 +101,16 @@ export async function tiltActor(name: string, position: number): Promise<void> {
     body: JSON.stringify({ position }),
   });
   if (!response.ok) {
-    throw new Error(`Failed to tilt actor ${name}`);
+    throw await commandError(response, `Failed to tilt actor ${name}`);
+  }
+}
+
+export async function stopActor(name: string): Promise<void> {
+  const response = await fetch(`${API_BASE}/actors/${encodeURIComponent(name)}/stop`, {
+    method: 'POST',
+  });
+  if (!response.ok) {
+    throw await commandError(response, `Failed to stop actor ${name}`);
   }
 }
 
@@ -56,3 +126,42 @@ export async function tiltAllActors(position: number): Promise<void> {
     throw new Error('Failed to tilt all actors');
   }
 }
+
+export async function fetchScenes(): Promise<SceneStatus[]> {
+  const response = await fetch(`${API_BASE}/scenes`);
+  if (!response.ok) {
+    throw new Error('Failed to fetch scenes');
+  }
+  return response.json();
+}
+
+export async function saveScene(name: string, actions: SceneAction[]): Promise<void> {
+  const response = await fetch(`${API_BASE}/scenes/${encodeURIComponent(name)}`, {
+    method: 'PUT',
+    headers: {
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify({ actions }),
+  });
+  if (!response.ok) {
+    throw new Error(await response.text());
+  }
+}
+
+export async function deleteScene(name: string): Promise<void> {
+  const response = await fetch(`${API_BASE}/scenes/${encodeURIComponent(name)}`, {
+    method: 'DELETE',
+  });
+  if (!response.ok) {
+    throw new Error(`Failed to delete scene ${name}`);
+  }
+}
+
+export async function activateScene(name: string): Promise<void> {
+  const response = await fetch(`${API_BASE}/scenes/${encodeURIComponent(name)}/activate`, {
+    method: 'POST',
+  });
+  if (!response.ok) {
+    throw new Error(`Failed to activate scene ${name}`);
+  }
+}
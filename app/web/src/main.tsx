@@ -1,13 +1,13 @@
 import React from 'react'
 import ReactDOM from 'react-dom/client'
-import { App } from './App.tsx'
+import { AuthGate } from './components/AuthGate.tsx'
 import { ThemeProvider } from './contexts/ThemeContext.tsx'
 import './index.css'
 
 ReactDOM.createRoot(document.getElementById('root')!).render(
   <React.StrictMode>
     <ThemeProvider>
-      <App />
+      <AuthGate />
     </ThemeProvider>
   </React.StrictMode>,
 )
@@ -1,3 +1,19 @@
+export type ActorHealth = 'pending' | 'online' | 'degraded' | 'offline' | 'reauthenticating';
+
+export type Direction = 'opening' | 'closing' | 'stopped';
+
+export type CommandSource = 'mqtt' | 'rest' | 'scene' | 'schedule' | 'protection' | 'external';
+
+export interface LockState {
+  locked: boolean;
+  reasons: string[];
+}
+
+export interface InterlockState {
+  contactOpen: boolean;
+  minPosition?: number;
+}
+
 export interface ActorStatus {
   name: string;
   displayName: string;
@@ -6,4 +22,12 @@ export interface ActorStatus {
   position: number;
   tilted: boolean;
   tiltPosition: number;
+  health: ActorHealth;
+  moving: boolean;
+  direction: Direction;
+  targetPosition?: number;
+  lastCommandSource?: CommandSource;
+  lock?: LockState;
+  interlock?: InterlockState;
+  updatedAt: string;
 }
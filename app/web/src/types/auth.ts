@@ -0,0 +1,12 @@
+export type Role = 'read' | 'control';
+
+export interface Principal {
+  name: string;
+  role: Role;
+}
+
+export interface AuthStatus {
+  enabled: boolean;
+  authenticated: boolean;
+  principal?: Principal;
+}
@@ -0,0 +1,17 @@
+import { CommandSource } from '@/types/actor';
+
+export type HistoryKind = 'position' | 'command';
+
+export type CommandStatus = 'queued' | 'running' | 'succeeded' | 'failed' | 'cancelled' | 'superseded';
+
+export interface HistoryEntry {
+  time: string;
+  kind: HistoryKind;
+  position: number;
+  tilted?: boolean;
+  source?: CommandSource;
+  action?: 'set' | 'tilt' | 'stop';
+  commandId?: string;
+  status?: CommandStatus;
+  error?: string;
+}
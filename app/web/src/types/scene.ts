@@ -0,0 +1,32 @@
+export type SceneActionType = 'open' | 'close' | 'set' | 'tilt' | 'closeandopenblinds' | 'stop';
+
+export interface SceneAction {
+  actor: string;
+  action: SceneActionType;
+  position: number;
+}
+
+export interface Scene {
+  name: string;
+  actions: SceneAction[];
+  source: 'config' | 'api';
+}
+
+export interface SceneActionResult {
+  actor: string;
+  action: SceneActionType;
+  status: 'ok' | 'failed' | 'notFound';
+  error?: string;
+}
+
+export interface SceneReport {
+  scene: string;
+  startedAt: string;
+  finishedAt: string;
+  success: boolean;
+  results: SceneActionResult[];
+}
+
+export interface SceneStatus extends Scene {
+  lastReport?: SceneReport;
+}
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,10 +14,21 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/mqtt-home/eltako-to-mqtt-gw/commands"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/config"
 	"github.com/mqtt-home/eltako-to-mqtt-gw/eltako"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/metrics"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/scenes"
+	"github.com/mqtt-home/eltako-to-mqtt-gw/scheduler"
 	"github.com/philipparndt/go-logger"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
+// sseClients counts the connected Server-Sent Events clients of all web servers
+var sseClients = metrics.Factory.NewGauge(prometheus.GaugeOpts{
+	Name: "eltako_sse_clients",
+	Help: "Number of connected Server-Sent Events clients",
+})
+
 // SSE client connection
 type SSEClient struct {
 	ID      string
@@ -25,20 +38,33 @@ type SSEClient struct {
 }
 
 type WebServer struct {
-	registry      *eltako.ActorRegistry
-	router        *chi.Mux
-	sseClients    map[string]*SSEClient
-	sseClients_mu sync.RWMutex
+	registry       *eltako.ActorRegistry
+	scenes         *scenes.Manager
+	schedules      *scheduler.Scheduler
+	stateMaxAge    time.Duration
+	allowedOrigins []string
+	auth           *Authenticator
+	router         *chi.Mux
+	sseClients     map[string]*SSEClient
+	sseClients_mu  sync.RWMutex
 }
 
 type ActorStatus struct {
-	Name         string `json:"name"`
-	DisplayName  string `json:"displayName"`
-	IP           string `json:"ip"`
-	Serial       string `json:"serial"`
-	Position     int    `json:"position"`
-	Tilted       bool   `json:"tilted"`
-	TiltPosition int    `json:"tiltPosition"`
+	Name              string                 `json:"name"`
+	DisplayName       string                 `json:"displayName"`
+	IP                string                 `json:"ip"`
+	Serial            string                 `json:"serial"`
+	Position          int                    `json:"position"`
+	Tilted            bool                   `json:"tilted"`
+	TiltPosition      int                    `json:"tiltPosition"`
+	Health            string                 `json:"health"`
+	Moving            bool                   `json:"moving"`
+	Direction         string                 `json:"direction"`
+	TargetPosition    *int                   `json:"targetPosition,omitempty"`
+	LastCommandSource commands.Source        `json:"lastCommandSource,omitempty"`
+	Lock              eltako.LockState       `json:"lock"`
+	Interlock         *eltako.InterlockState `json:"interlock,omitempty"`
+	UpdatedAt         time.Time              `json:"updatedAt"`
 }
 
 type TiltRequest struct {
@@ -49,11 +75,16 @@ type SetPositionRequest struct {
 	Position int `json:"position"`
 }
 
-func NewWebServer(registry *eltako.ActorRegistry) *WebServer {
+func NewWebServer(registry *eltako.ActorRegistry, sceneManager *scenes.Manager, schedules *scheduler.Scheduler, cfg config.WebConfig) *WebServer {
 	ws := &WebServer{
-		registry:   registry,
-		router:     chi.NewRouter(),
-		sseClients: make(map[string]*SSEClient),
+		registry:       registry,
+		scenes:         sceneManager,
+		schedules:      schedules,
+		stateMaxAge:    time.Duration(cfg.StateMaxAge) * time.Millisecond,
+		allowedOrigins: cfg.AllowedOrigins,
+		auth:           NewAuthenticator(cfg.Auth),
+		router:         chi.NewRouter(),
+		sseClients:     make(map[string]*SSEClient),
 	}
 	ws.setupRoutes()
 	return ws
@@ -63,29 +94,59 @@ func (ws *WebServer) setupRoutes() {
 	ws.router.Use(middleware.Logger)
 	ws.router.Use(middleware.Recoverer)
 
-	// CORS configuration
+	// CORS configuration. Cookies are only accepted from explicitly allowed origins.
+	allowCredentials := !slices.Contains(ws.allowedOrigins, "*")
 	ws.router.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"*"},
+		AllowedOrigins:   ws.allowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: false,
+		AllowCredentials: allowCredentials,
 		MaxAge:           300,
 	}))
 
+	if ws.auth != nil {
+		logger.Info("Authentication of the web interface and REST API enabled")
+		ws.router.Use(ws.auth.Middleware)
+	}
+
 	// API routes
 	ws.router.Route("/api", func(r chi.Router) {
+		r.Get("/auth/status", ws.getAuthStatus)
+		r.Post("/auth/login", ws.login)
+		r.Post("/auth/logout", ws.logout)
 		r.Get("/actors", ws.getAllActors)
 		r.Get("/actors/{actorName}", ws.getActor)
 		r.Post("/actors/{actorName}/position", ws.setActorPosition)
 		r.Post("/actors/{actorName}/tilt", ws.tiltActor)
+		r.Post("/actors/{actorName}/stop", ws.stopActor)
+		r.Get("/actors/{actorName}/commands", ws.getActorCommands)
+		r.Get("/actors/{actorName}/history", ws.getActorHistory)
 		r.Post("/actors/all/tilt", ws.tiltAllActors)
+		r.Get("/commands/{commandId}", ws.getCommand)
+		r.Get("/groups", ws.getAllGroups)
+		r.Get("/groups/{groupName}", ws.getGroup)
+		r.Post("/groups/{groupName}/{action}", ws.applyGroupAction)
+		r.Get("/scenes", ws.getAllScenes)
+		r.Get("/scenes/{sceneName}", ws.getScene)
+		r.Put("/scenes/{sceneName}", ws.putScene)
+		r.Delete("/scenes/{sceneName}", ws.deleteScene)
+		r.Post("/scenes/{sceneName}/activate", ws.activateScene)
+		r.Get("/schedules", ws.getAllSchedules)
+		r.Get("/schedules/{scheduleName}", ws.getSchedule)
+		r.Post("/schedules/{scheduleName}/enable", ws.enableSchedule)
+		r.Post("/schedules/{scheduleName}/disable", ws.disableSchedule)
+		r.Get("/sun", ws.getSun)
 		r.Get("/events", ws.handleSSE)
 	})
 
 	// SSE route
 	ws.router.Get("/events", ws.handleSSE)
 
+	ws.router.Handle("/metrics", metrics.Handler())
+	ws.router.Get("/healthz", ws.getHealthz)
+	ws.router.Get("/readyz", ws.getReadyz)
+
 	// Serve static files (React app)
 	fileServer := http.FileServer(http.Dir("./web/dist/"))
 	ws.router.Handle("/*", fileServer)
@@ -94,24 +155,9 @@ func (ws *WebServer) setupRoutes() {
 func (ws *WebServer) getAllActors(w http.ResponseWriter, r *http.Request) {
 	var actors []ActorStatus
 
-	for _, actor := range ws.registry.Actors {
-		// Get current position
-		position, err := actor.GetPosition()
-		if err != nil {
-			logger.Error("Failed to get position for actor", actor.Name, err)
-			position = actor.Position // fallback to cached position
-		}
-
-		status := ActorStatus{
-			Name:         actor.Name,
-			DisplayName:  actor.DisplayName(),
-			IP:           actor.IP,
-			Serial:       actor.Serial,
-			Position:     position,
-			Tilted:       actor.Tilted,
-			TiltPosition: actor.TiltPosition,
-		}
-		actors = append(actors, status)
+	refresh := isRefreshRequested(r)
+	for _, actor := range ws.registry.AllActors() {
+		actors = append(actors, ws.actorStatus(actor, refresh))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -127,22 +173,7 @@ func (ws *WebServer) getActor(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get current position
-	position, err := actor.GetPosition()
-	if err != nil {
-		logger.Error("Failed to get position for actor", actor.Name, err)
-		position = actor.Position // fallback to cached position
-	}
-
-	status := ActorStatus{
-		Name:         actor.Name,
-		DisplayName:  actor.DisplayName(),
-		IP:           actor.IP,
-		Serial:       actor.Serial,
-		Position:     position,
-		Tilted:       actor.Tilted,
-		TiltPosition: actor.TiltPosition,
-	}
+	status := ws.actorStatus(actor, isRefreshRequested(r))
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(status)
@@ -169,22 +200,14 @@ func (ws *WebServer) setActorPosition(w http.ResponseWriter, r *http.Request) {
 	}
 
 	command := commands.LLCommand{
+		ID:       commands.NewID(),
 		Action:   commands.LLActionSet,
 		Position: req.Position,
+		Source:   commands.SourceREST,
 	}
 
-	go actor.Apply(command)
-
-	logger.Info(fmt.Sprintf("Set position for actor %s to %d", actorName, req.Position))
-
-	// Broadcast state change after a brief delay to allow the actor to update
-	go func() {
-		time.Sleep(500 * time.Millisecond)
-		ws.broadcastStateChange()
-	}()
-
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+	logger.Info(fmt.Sprintf("Set position for actor %s to %d", actorName, req.Position), command.ID)
+	ws.dispatch(w, r, actor, command)
 }
 
 func (ws *WebServer) tiltActor(w http.ResponseWriter, r *http.Request) {
@@ -208,22 +231,33 @@ func (ws *WebServer) tiltActor(w http.ResponseWriter, r *http.Request) {
 	}
 
 	command := commands.LLCommand{
+		ID:       commands.NewID(),
 		Action:   commands.LLActionTilt,
 		Position: req.Position,
+		Source:   commands.SourceREST,
 	}
 
-	go actor.Apply(command)
+	logger.Info(fmt.Sprintf("Tilt actor %s to position %d", actorName, req.Position), command.ID)
+	ws.dispatch(w, r, actor, command)
+}
 
-	logger.Info(fmt.Sprintf("Tilt actor %s to position %d", actorName, req.Position))
+func (ws *WebServer) stopActor(w http.ResponseWriter, r *http.Request) {
+	actorName := chi.URLParam(r, "actorName")
+	actor := ws.registry.GetActor(actorName)
 
-	// Broadcast state change after a brief delay to allow the actor to update
-	go func() {
-		time.Sleep(500 * time.Millisecond)
-		ws.broadcastStateChange()
-	}()
+	if actor == nil {
+		http.Error(w, fmt.Sprintf("Actor '%s' not found", actorName), http.StatusNotFound)
+		return
+	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+	command := commands.LLCommand{
+		ID:     commands.NewID(),
+		Action: commands.LLActionStop,
+		Source: commands.SourceREST,
+	}
+
+	logger.Info(fmt.Sprintf("Stop actor %s", actorName), command.ID)
+	ws.dispatch(w, r, actor, command)
 }
 
 func (ws *WebServer) tiltAllActors(w http.ResponseWriter, r *http.Request) {
@@ -241,11 +275,17 @@ func (ws *WebServer) tiltAllActors(w http.ResponseWriter, r *http.Request) {
 	command := commands.LLCommand{
 		Action:   commands.LLActionTilt,
 		Position: req.Position,
+		Source:   commands.SourceREST,
 	}
 
 	tiltedCount := 0
-	for _, actor := range ws.registry.Actors {
-		go actor.Apply(command)
+	refused := map[string]string{}
+	for _, actor := range ws.registry.AllActors() {
+		// Refused actors are skipped, the others are tilted anyway
+		if _, err := actor.Submit(command); err != nil {
+			refused[actor.Name] = err.Error()
+			continue
+		}
 		tiltedCount++
 	}
 
@@ -257,10 +297,79 @@ func (ws *WebServer) tiltAllActors(w http.ResponseWriter, r *http.Request) {
 		ws.broadcastStateChange()
 	}()
 
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"count":   tiltedCount,
+		"refused": refused,
+	})
+}
+
+func (ws *WebServer) getAllGroups(w http.ResponseWriter, r *http.Request) {
+	groups := []eltako.GroupState{}
+	for _, group := range ws.registry.AllGroups() {
+		groups = append(groups, group.State())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groups)
+}
+
+func (ws *WebServer) getGroup(w http.ResponseWriter, r *http.Request) {
+	groupName := chi.URLParam(r, "groupName")
+	group := ws.registry.GetGroup(groupName)
+
+	if group == nil {
+		http.Error(w, fmt.Sprintf("Group '%s' not found", groupName), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(group.State())
+}
+
+func (ws *WebServer) applyGroupAction(w http.ResponseWriter, r *http.Request) {
+	groupName := chi.URLParam(r, "groupName")
+	group := ws.registry.GetGroup(groupName)
+
+	if group == nil {
+		http.Error(w, fmt.Sprintf("Group '%s' not found", groupName), http.StatusNotFound)
+		return
+	}
+
+	// The body is optional, it is only needed for actions with a position
+	var req SetPositionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if req.Position < 0 || req.Position > 100 {
+		http.Error(w, "Position must be between 0 and 100", http.StatusBadRequest)
+		return
+	}
+
+	action := commands.Action{
+		Action:   commands.ActionType(strings.ToLower(chi.URLParam(r, "action"))),
+		Position: req.Position,
+	}
+	command, err := action.Validate()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid action '%s'", action.Action), http.StatusBadRequest)
+		return
+	}
+	command.Source = commands.SourceREST
+
+	go group.Apply(command)
+
+	logger.Info(fmt.Sprintf("Apply %s to group %s", action.Action, groupName))
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"status": "success",
-		"count":  tiltedCount,
+		"count":  len(group.Actors()),
 	})
 }
 
@@ -269,7 +378,6 @@ func (ws *WebServer) handleSSE(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
 	// Generate a unique ID for the client
 	clientID := fmt.Sprintf("%d", time.Now().UnixNano())
@@ -287,6 +395,7 @@ func (ws *WebServer) handleSSE(w http.ResponseWriter, r *http.Request) {
 		Writer:  w,
 	}
 	ws.sseClients_mu.Unlock()
+	sseClients.Inc()
 
 	// Send initial state
 	actorsState := ws.getAllActorsState()
@@ -302,6 +411,9 @@ func (ws *WebServer) handleSSE(w http.ResponseWriter, r *http.Request) {
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
 
+	events := eltako.Events.Subscribe("sse-"+clientID, 100)
+	defer events.Close()
+
 	// Handle client connection
 	defer func() {
 		logger.Info(fmt.Sprintf("SSE client disconnected: %s", clientID))
@@ -309,12 +421,16 @@ func (ws *WebServer) handleSSE(w http.ResponseWriter, r *http.Request) {
 		delete(ws.sseClients, clientID)
 		close(channel)
 		ws.sseClients_mu.Unlock()
+		sseClients.Dec()
 	}()
 
 	for {
 		select {
-		case msg := <-eltako.PositionChangeChan:
-			logger.Debug("Received position change event", msg.ActorName, msg.Position)
+		case event := <-events.C:
+			if !isStateEvent(event.Type) {
+				continue
+			}
+			logger.Debug("Received actor event", event.Type, event.Actor.Name)
 			actorsState := ws.getAllActorsState()
 			message, _ := json.Marshal(actorsState)
 			fmt.Fprintf(w, "data: %s\n\n", string(message))
@@ -339,6 +455,16 @@ func (ws *WebServer) handleSSE(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// isStateEvent checks if the event changes the state shown to SSE clients
+func isStateEvent(eventType eltako.EventType) bool {
+	switch eventType {
+	case eltako.EventPositionChanged, eltako.EventMovementStarted, eltako.EventMovementStopped,
+		eltako.EventTiltChanged, eltako.EventHealthChanged, eltako.EventLockChanged, eltako.EventInterlockChanged:
+		return true
+	}
+	return false
+}
+
 // Broadcast state changes to all SSE clients
 func (ws *WebServer) broadcastStateChange() {
 	actorsState := ws.getAllActorsState()
@@ -370,27 +496,50 @@ func (ws *WebServer) broadcastStateChange() {
 func (ws *WebServer) getAllActorsState() []ActorStatus {
 	var actorsState []ActorStatus
 
-	for _, actor := range ws.registry.Actors {
-		// Get current position
-		position, err := actor.GetPosition()
+	for _, actor := range ws.registry.AllActors() {
+		actorsState = append(actorsState, ws.actorStatus(actor, false))
+	}
+
+	return actorsState
+}
+
+// isRefreshRequested checks for ?refresh=true which forces a live read from the device
+func isRefreshRequested(r *http.Request) bool {
+	refresh, _ := strconv.ParseBool(r.URL.Query().Get("refresh"))
+	return refresh
+}
+
+func (ws *WebServer) actorStatus(actor *eltako.ShadingActor, refresh bool) ActorStatus {
+	if refresh {
+		_, err := actor.GetPosition()
 		if err != nil {
 			logger.Error("Failed to get position for actor", actor.Name, err)
-			position = actor.Position // fallback to cached position
 		}
+	}
 
-		state := ActorStatus{
-			Name:         actor.Name,
-			DisplayName:  actor.DisplayName(),
-			IP:           actor.IP,
-			Serial:       actor.Serial,
-			Position:     position,
-			Tilted:       actor.Tilted,
-			TiltPosition: actor.TiltPosition,
-		}
-		actorsState = append(actorsState, state)
+	state := actor.CachedState()
+	// Pending and offline actors are not asked; their cached position is used
+	if !refresh && state.IsStale(ws.stateMaxAge) && actor.IsAvailable() {
+		actor.Refresh()
 	}
 
-	return actorsState
+	return ActorStatus{
+		Name:              actor.Name,
+		DisplayName:       actor.DisplayName(),
+		IP:                actor.Address(),
+		Serial:            actor.Serial,
+		Position:          state.Position,
+		Tilted:            state.Tilted,
+		TiltPosition:      state.TiltPosition,
+		Health:            string(state.Health),
+		Moving:            state.Moving,
+		Direction:         string(state.Direction),
+		TargetPosition:    state.TargetPosition,
+		LastCommandSource: state.LastCommandSource,
+		Lock:              actor.LockState(),
+		Interlock:         actor.Interlock(),
+		UpdatedAt:         state.UpdatedAt,
+	}
 }
 
 func (ws *WebServer) Start(port int) error {